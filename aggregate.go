@@ -0,0 +1,282 @@
+package otree
+
+// Aggregate describes a user defined aggregate that is cached for every node
+// once aggregates are enabled. The aggregate of a node is Value(nd) combined
+// with the aggregates of its siblings, in their order:
+// Combine(...Combine(Combine(Value(nd), agg0), agg1)..., aggN).
+type Aggregate struct {
+	Name    string                             // name used to retrieve the value
+	Value   func(nd *Node) interface{}         // value of a single node
+	Combine func(a, b interface{}) interface{} // associative combination
+}
+
+// aggregates holds the cached aggregates of a (sub)tree.
+type aggregates struct {
+	defs    []Aggregate   // user defined aggregates, shared within a tree
+	size    int           // number of nodes
+	height  int           // longest downward path to a leaf
+	tallest int           // number of siblings whose height is height-1
+	leaves  int           // number of leaves
+	values  []interface{} // values of the user defined aggregates
+}
+
+// EnableAggregates enables the cached aggregates for the tree starting at the
+// root of node. Once enabled, Size, Breadth, Height and nd.Height() no longer
+// walk the tree. The caches are updated along the ancestor chain by Link,
+// RemoveSibling, RemoveAllSiblings and ReplaceSibling. Nodes linked into the
+// tree get their aggregates computed. The size, breadth and height are
+// updated by their differences, so a change costs O(depth). The user defined
+// aggregates of an ancestor are combined again from the ancestor's siblings.
+// defs are the user defined aggregates, that can be retrieved by
+// nd.Aggregate().
+func EnableAggregates(node *Node, defs ...Aggregate) {
+	defs = append([]Aggregate{}, defs...)
+	node.Root().enableAggregates(defs)
+}
+
+// DisableAggregates removes the cached aggregates from the tree starting at
+// the root of node.
+func DisableAggregates(node *Node) {
+	f := func(nd *Node, data interface{}) {
+		nd.agg = nil
+	}
+	node.Root().Walk(f, nil)
+}
+
+// Aggregate returns the value of nd's user defined aggregate name. If the
+// aggregates are not enabled ErrAggregatesDisabled will be returned. If there
+// is no aggregate with that name ErrUnknownAggregate will be returned.
+func (nd *Node) Aggregate(name string) (interface{}, error) {
+	if nd.agg == nil {
+		return nil, ErrAggregatesDisabled
+	}
+	for i, def := range nd.agg.defs {
+		if def.Name == name {
+			return nd.agg.values[i], nil
+		}
+	}
+	return nil, ErrUnknownAggregate
+}
+
+// RefreshAggregates recomputes the aggregates of nd and its ancestors. It must
+// be called after changing nd's data when user defined aggregates depend on
+// it. If the aggregates are not enabled ErrAggregatesDisabled will be
+// returned.
+func (nd *Node) RefreshAggregates() error {
+	if nd.agg == nil {
+		return ErrAggregatesDisabled
+	}
+	nd.childrenChanged()
+	return nil
+}
+
+// enableAggregates computes the aggregates for nd and all of its descendants.
+func (nd *Node) enableAggregates(defs []Aggregate) {
+	for _, sbl := range nd.Siblings() {
+		sbl.enableAggregates(defs)
+	}
+	nd.agg = &aggregates{defs: defs}
+	nd.computeAggregates()
+}
+
+// computeAggregates computes nd's aggregates from the aggregates of its
+// siblings.
+func (nd *Node) computeAggregates() {
+	a := nd.agg
+	a.size, a.leaves = 1, 0
+	if nd.IsLeaf() {
+		a.leaves = 1
+	}
+	nd.forSiblings(func(sbl *Node) {
+		a.size += sbl.agg.size
+		a.leaves += sbl.agg.leaves
+	})
+	nd.computeHeight()
+	nd.computeValues()
+}
+
+// computeValues computes the values of nd's user defined aggregates from the
+// values of its siblings.
+func (nd *Node) computeValues() {
+	a := nd.agg
+	if len(a.defs) == 0 {
+		return
+	}
+	if a.values == nil {
+		a.values = make([]interface{}, len(a.defs))
+	}
+	for i, def := range a.defs {
+		a.values[i] = def.Value(nd)
+	}
+	nd.forSiblings(func(sbl *Node) {
+		for i, def := range a.defs {
+			a.values[i] = def.Combine(a.values[i], sbl.agg.values[i])
+		}
+	})
+}
+
+// computeHeight computes nd's height from the heights of its siblings.
+func (nd *Node) computeHeight() {
+	a := nd.agg
+	a.height, a.tallest = 0, 0
+	nd.forSiblings(func(sbl *Node) {
+		a.addHeight(sbl.agg.height)
+	})
+}
+
+// addHeight updates the height for a sibling with height h that is added.
+func (a *aggregates) addHeight(h int) {
+	switch {
+	case h+1 > a.height:
+		a.height, a.tallest = h+1, 1
+	case h+1 == a.height:
+		a.tallest++
+	}
+}
+
+// removeHeight updates the height for a sibling with height h that is
+// removed. It returns false if the height must be computed again from the
+// remaining siblings.
+func (a *aggregates) removeHeight(h int) bool {
+	if h+1 == a.height {
+		a.tallest--
+	}
+	return a.tallest > 0
+}
+
+// childrenChanged updates the aggregates of nd and its ancestors after the
+// order of its siblings or its data has been changed. nd's aggregates are
+// computed again from its siblings.
+func (nd *Node) childrenChanged() {
+	a := nd.agg
+	if a == nil {
+		return
+	}
+	size, leaves, height := a.size, a.leaves, a.height
+	nd.computeAggregates()
+	nd.propagate(size, leaves, height)
+}
+
+// subtreeChanged updates the aggregates of nd, its descendants and its
+// ancestors after the structure of nd's subtree has been changed.
+func (nd *Node) subtreeChanged() {
+	a := nd.agg
+	if a == nil {
+		return
+	}
+	size, leaves, height := a.size, a.leaves, a.height
+	nd.traverse(true, false, func(node *Node, level int) bool {
+		node.computeAggregates()
+		return true
+	})
+	nd.propagate(size, leaves, height)
+}
+
+// linked enables the aggregates for nodes that are linked to nd, if needed,
+// and updates the aggregates of nd and its ancestors.
+func (nd *Node) linked(nodes []*Node) {
+	nd.siblingsChanged(nodes, nil)
+}
+
+// unlinked updates the aggregates of nd and its ancestors after nodes have
+// been removed from nd.
+func (nd *Node) unlinked(nodes ...*Node) {
+	nd.siblingsChanged(nil, nodes)
+}
+
+// siblingsChanged updates the aggregates of nd and its ancestors after added
+// have been linked to nd and removed have been removed from it. It enables
+// the aggregates for the added nodes, if needed. The size and the number of
+// leaves are updated by the differences. The height is only computed again
+// from the siblings when the last of the highest siblings is removed. The
+// user defined aggregates of nd and its ancestors are always computed again.
+func (nd *Node) siblingsChanged(added, removed []*Node) {
+	a := nd.agg
+	if a == nil {
+		return
+	}
+	for _, n := range added {
+		if n.agg == nil || !sameDefs(n.agg.defs, a.defs) {
+			n.enableAggregates(a.defs)
+		}
+	}
+
+	size, leaves, height := a.size, a.leaves, a.height
+	if nd.Degree()-len(added)+len(removed) == 0 {
+		// nd was a leaf itself
+		a.leaves = 0
+	}
+	valid := true
+	for _, n := range removed {
+		a.size -= n.agg.size
+		a.leaves -= n.agg.leaves
+		valid = a.removeHeight(n.agg.height) && valid
+	}
+	for _, n := range added {
+		a.size += n.agg.size
+		a.leaves += n.agg.leaves
+		a.addHeight(n.agg.height)
+	}
+	switch {
+	case nd.IsLeaf():
+		a.leaves, a.height, a.tallest = 1, 0, 0
+	case !valid:
+		nd.computeHeight()
+	}
+
+	nd.computeValues()
+	nd.propagate(size, leaves, height)
+}
+
+// propagate updates the aggregates of nd's ancestors after nd's aggregates
+// have been changed from the provided size, number of leaves and height. It
+// stops at the first ancestor whose aggregates don't change.
+func (nd *Node) propagate(size, leaves, height int) {
+	dSize, dLeaves := nd.agg.size-size, nd.agg.leaves-leaves
+	for c, p := nd, nd.parent; p != nil && p.agg != nil; c, p = p, p.parent {
+		a := p.agg
+		h := c.agg.height
+		if dSize == 0 && dLeaves == 0 && h == height && len(a.defs) == 0 {
+			return
+		}
+
+		old := a.height
+		a.size += dSize
+		a.leaves += dLeaves
+		switch {
+		case h == height:
+		case h+1 > old:
+			a.addHeight(h)
+		default:
+			// c's old height is replaced by its new one
+			a.removeHeight(height)
+			if a.addHeight(h); a.tallest == 0 {
+				p.computeHeight()
+			}
+		}
+		p.computeValues()
+		height = old
+	}
+}
+
+// forSiblings calls f for each of nd's siblings, in their order, without
+// allocating a slice.
+func (nd *Node) forSiblings(f func(sbl *Node)) {
+	if nd.list != nil {
+		for n := nd.list.first; n != nil; n = n.next {
+			f(n)
+		}
+		return
+	}
+	for _, n := range nd.siblings {
+		f(n)
+	}
+}
+
+// sameDefs tells if a and b hold the same aggregate definitions.
+func sameDefs(a, b []Aggregate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
@@ -0,0 +1,172 @@
+package otree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func sumAggregate() Aggregate {
+	return Aggregate{
+		Name:    "sum",
+		Value:   func(nd *Node) interface{} { return nd.Data.(int) },
+		Combine: func(a, b interface{}) interface{} { return a.(int) + b.(int) },
+	}
+}
+
+// checkAggregates compares the cached aggregates of the tree holding nd with
+// the results of walking the tree.
+func checkAggregates(t *testing.T, step string, nd *Node) {
+	t.Helper()
+
+	sum := 0
+	nd.Walk(func(n *Node, data interface{}) { sum += n.Data.(int) }, nil)
+
+	root := nd.Root()
+	agg := root.agg
+	root.agg = nil
+	size, breadth, height := Size(root, false), Breadth(root, false), root.Height()
+	root.agg = agg
+
+	if got := Size(root, false); got != size {
+		t.Errorf("%s: Size() returns %d, should be %d", step, got, size)
+	}
+	if got := Breadth(root, false); got != breadth {
+		t.Errorf("%s: Breadth() returns %d, should be %d", step, got, breadth)
+	}
+	if got := Height(root, false); got != height {
+		t.Errorf("%s: Height() returns %d, should be %d", step, got, height)
+	}
+	if got, err := nd.Aggregate("sum"); err != nil || got != sum {
+		t.Errorf("%s: Aggregate(\"sum\") returns %v, %v, should be %d, nil",
+			step, got, err, sum)
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	root := New(0)
+	if _, err := root.Aggregate("sum"); !errors.Is(err, ErrAggregatesDisabled) {
+		t.Errorf("Aggregate() returns error %v, should be %q", err, ErrAggregatesDisabled)
+	}
+
+	children := []*Node{New(1), New(2), New(3)}
+	root.Link(AtEnd, children...)
+	children[0].Link(AtEnd, New(4), New(5))
+
+	EnableAggregates(children[1], sumAggregate())
+	checkAggregates(t, "enable", root)
+
+	if _, err := root.Aggregate("product"); !errors.Is(err, ErrUnknownAggregate) {
+		t.Errorf("Aggregate() returns error %v, should be %q", err, ErrUnknownAggregate)
+	}
+
+	sub := New(6)
+	sub.Link(AtEnd, New(7))
+	sub.siblings[0].Link(AtEnd, New(8))
+	children[2].Link(AtEnd, sub)
+	checkAggregates(t, "link", root)
+
+	children[0].RemoveSibling(0)
+	checkAggregates(t, "remove sibling", root)
+
+	children[2].ReplaceSibling(0, New(9), New(10))
+	checkAggregates(t, "replace sibling", root)
+	checkAggregates(t, "replaced subtree", sub)
+
+	children[0].RemoveAllSiblings()
+	checkAggregates(t, "remove all siblings", root)
+
+	children[1].Data = 20
+	children[1].RefreshAggregates()
+	checkAggregates(t, "refresh", root)
+
+	DisableAggregates(root)
+	if _, err := children[1].Aggregate("sum"); !errors.Is(err, ErrAggregatesDisabled) {
+		t.Errorf("Aggregate() returns error %v, should be %q", err, ErrAggregatesDisabled)
+	}
+	if err := root.RefreshAggregates(); !errors.Is(err, ErrAggregatesDisabled) {
+		t.Errorf("RefreshAggregates() returns error %v, should be %q", err, ErrAggregatesDisabled)
+	}
+}
+
+func TestAggregatesRandomChanges(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, list := range []bool{false, true} {
+		root := New(0)
+		root.SetListStorage(list)
+		EnableAggregates(root, sumAggregate())
+		nodes := []*Node{root}
+
+		for step := 0; step < 2000; step++ {
+			nd := nodes[rnd.Intn(len(nodes))]
+			switch rnd.Intn(4) {
+			case 0, 1:
+				n := New(step)
+				n.SetListStorage(list)
+				nd.Link(rnd.Intn(nd.Degree()+1), n)
+				nodes = append(nodes, n)
+			case 2:
+				if d := nd.Degree(); d > 0 {
+					nd.RemoveSibling(rnd.Intn(d))
+				}
+			case 3:
+				if d := nd.Degree(); d > 0 {
+					nd.Splice(rnd.Intn(d), rnd.Intn(3), New(step))
+				}
+			}
+			// forget the nodes that are no longer part of the tree
+			kept := nodes[:0]
+			for _, n := range nodes {
+				if n.Root() == root {
+					kept = append(kept, n)
+				}
+			}
+			nodes = kept
+			checkAggregates(t, fmt.Sprintf("list %t, step %d", list, step), root)
+			for _, n := range nodes {
+				size, leaves, height := 0, 0, 0
+				n.walk(func(nd *Node, level int) bool {
+					size++
+					if nd.IsLeaf() {
+						leaves++
+					}
+					if level > height {
+						height = level
+					}
+					return true
+				})
+				if a := n.agg; a.size != size || a.leaves != leaves || a.height != height {
+					t.Errorf("list %t, step %d: node %v caches %d, %d, %d, should be %d, %d, %d",
+						list, step, n.Data, a.size, a.leaves, a.height, size, leaves, height)
+				}
+			}
+			if t.Failed() {
+				return
+			}
+		}
+	}
+}
+
+func TestAggregatesManyRemovals(t *testing.T) {
+	const n = 100000
+	for _, list := range []bool{false, true} {
+		root := New(0)
+		root.SetListStorage(list)
+		for i := 0; i < n; i++ {
+			root.adopt(New(i))
+		}
+		EnableAggregates(root)
+
+		for i := 0; i < n; i++ {
+			index := 0
+			if i%2 == 1 {
+				index = root.Degree() - 1
+			}
+			root.RemoveSibling(index)
+		}
+		if got := Size(root, false); got != 1 || Breadth(root, false) != 1 || Height(root, false) != 0 {
+			t.Errorf("list %t: removing all siblings results in size %d", list, got)
+		}
+	}
+}
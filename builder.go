@@ -0,0 +1,296 @@
+package otree
+
+// DefaultChunkSize is the number of nodes, or sibling slots, a Builder
+// allocates at once when no chunk size is given.
+const DefaultChunkSize = 1024
+
+// Builder builds trees with few allocations. Nodes and sibling slices are
+// taken from chunks that hold many of them, so building a large tree needs a
+// fraction of the allocations New and Link need. Sibling slices get exactly
+// the size of the number of siblings. The nodes are ordinary nodes that can be
+// used with all functions and methods of this package.
+// A chunk stays in memory as long as any of its nodes is in use. The zero
+// value is an empty Builder that uses DefaultChunkSize.
+type Builder struct {
+	size      int       // chunk size, 0 for DefaultChunkSize
+	nodes     [][]Node  // node chunks
+	nodeCount int       // number of node chunks in use
+	nodeUsed  int       // number of nodes used in the last chunk in use
+	slots     [][]*Node // sibling slot chunks
+	slotCount int       // number of slot chunks in use
+	slotUsed  int       // number of slots used in the last chunk in use
+	open      []*Node   // nodes started by Begin and not yet ended
+	starts    []int     // index in pending of the first sibling of each open node
+	pending   []*Node   // finished nodes waiting for their parent
+	err       error     // first error found by Begin, End or Leaf
+}
+
+// EventKind identifies the kind of an event, see Event.
+type EventKind int
+
+const (
+	EventBegin EventKind = iota // a node with siblings starts
+	EventEnd                    // the last started node ends
+	EventLeaf                   // a node without siblings
+)
+
+// String returns the name of k.
+func (k EventKind) String() string {
+	switch k {
+	case EventBegin:
+		return "Begin"
+	case EventEnd:
+		return "End"
+	case EventLeaf:
+		return "Leaf"
+	}
+	return "Unknown"
+}
+
+// Event describes a step in building a tree. A node with siblings is
+// described by an EventBegin, the events of its siblings and an EventEnd. A
+// leaf is described by an EventLeaf. Data is the node's data, it is nil for
+// EventEnd.
+type Event struct {
+	Kind EventKind
+	Data interface{}
+}
+
+// NewBuilder returns a Builder that allocates chunkSize nodes, or sibling
+// slots, at once. If chunkSize isn't positive DefaultChunkSize is used.
+func NewBuilder(chunkSize int) *Builder {
+	if chunkSize < 0 {
+		chunkSize = 0
+	}
+	return &Builder{size: chunkSize}
+}
+
+// New returns a new node with some data stored into it, like the function
+// New.
+func (b *Builder) New(data interface{}) *Node {
+	nd := b.alloc()
+	nd.Data = data
+	return nd
+}
+
+// Node returns a new node with some data stored into it and with siblings as
+// its siblings. The siblings must be roots of other trees. If one of them has
+// a parent ErrNodeHasParent will be returned, if one of them occurs more than
+// once ErrDuplicateNodeFound.
+func (b *Builder) Node(data interface{}, siblings ...*Node) (*Node, error) {
+	nd := b.New(data)
+	for i, sbl := range siblings {
+		var err error
+		switch sbl.parent {
+		case nil:
+		case nd:
+			err = ErrDuplicateNodeFound
+		default:
+			err = ErrNodeHasParent
+		}
+		if err != nil {
+			for _, s := range siblings[:i] {
+				s.parent = nil
+			}
+			return nil, err
+		}
+		sbl.parent = nd
+	}
+
+	b.setSiblings(nd, siblings)
+	return nd, nil
+}
+
+// Begin starts a new node with some data stored into it. The nodes started
+// or added until the matching call to End become its siblings.
+func (b *Builder) Begin(data interface{}) {
+	if b.err != nil {
+		return
+	}
+	b.open = append(b.open, b.New(data))
+	b.starts = append(b.starts, len(b.pending))
+}
+
+// End ends the node started by the last call to Begin that has not ended yet.
+// If there is no such node Result will return ErrUnbalanced.
+func (b *Builder) End() {
+	if b.err != nil {
+		return
+	}
+	l := len(b.open)
+	if l == 0 {
+		b.err = ErrUnbalanced
+		return
+	}
+	nd, start := b.open[l-1], b.starts[l-1]
+	b.open, b.starts = b.open[:l-1], b.starts[:l-1]
+
+	siblings := b.pending[start:]
+	for _, sbl := range siblings {
+		sbl.parent = nd
+	}
+	b.setSiblings(nd, siblings)
+	b.pending = append(b.pending[:start], nd)
+}
+
+// Leaf adds a new node without siblings with some data stored into it.
+func (b *Builder) Leaf(data interface{}) {
+	if b.err != nil {
+		return
+	}
+	b.pending = append(b.pending, b.New(data))
+}
+
+// Emit calls Begin, End or Leaf for e. It returns the first error found so
+// far. Events(root, b.Emit) followed by Result copies the tree starting at
+// root.
+func (b *Builder) Emit(e Event) error {
+	switch e.Kind {
+	case EventBegin:
+		b.Begin(e.Data)
+	case EventEnd:
+		b.End()
+	case EventLeaf:
+		b.Leaf(e.Data)
+	}
+	return b.err
+}
+
+// Result returns the root of the tree built by the calls to Begin, End and
+// Leaf. If they are not balanced ErrUnbalanced will be returned, if they
+// didn't build a single tree ErrNoSingleRoot. Afterwards b is ready to build
+// the next tree.
+func (b *Builder) Result() (*Node, error) {
+	err := b.err
+	switch {
+	case err != nil:
+	case len(b.open) > 0:
+		err = ErrUnbalanced
+	case len(b.pending) != 1:
+		err = ErrNoSingleRoot
+	}
+
+	var root *Node
+	if err == nil {
+		root = b.pending[0]
+	}
+	b.open, b.starts, b.pending, b.err = b.open[:0], b.starts[:0], b.pending[:0], nil
+	return root, err
+}
+
+// Events calls f for each event that describes the tree starting at root, in
+// the order in which they are needed to build it with a Builder. If f returns
+// an error Events stops and returns it.
+func Events(root *Node, f func(e Event) error) error {
+	type frame struct {
+		siblings []*Node
+		next     int
+	}
+	var stack []frame
+
+	nd := root
+	for {
+		if nd != nil {
+			if nd.IsLeaf() {
+				if err := f(Event{EventLeaf, nd.Data}); err != nil {
+					return err
+				}
+			} else {
+				if err := f(Event{EventBegin, nd.Data}); err != nil {
+					return err
+				}
+				stack = append(stack, frame{siblings: nd.Siblings()})
+			}
+		}
+
+		l := len(stack)
+		if l == 0 {
+			return nil
+		}
+		top := &stack[l-1]
+		if top.next < len(top.siblings) {
+			nd = top.siblings[top.next]
+			top.next++
+			continue
+		}
+		if err := f(Event{Kind: EventEnd}); err != nil {
+			return err
+		}
+		stack = stack[:l-1]
+		nd = nil
+	}
+}
+
+// Reset recycles all nodes and sibling slices allocated by b, so they are
+// used again for new nodes. The nodes built before must no longer be used.
+// A tree that is being built by Begin, End and Leaf is discarded.
+func (b *Builder) Reset() {
+	b.Result()
+	for _, chunk := range b.nodes[:b.nodeCount] {
+		for i := range chunk {
+			chunk[i] = Node{}
+		}
+	}
+	for _, chunk := range b.slots[:b.slotCount] {
+		for i := range chunk {
+			chunk[i] = nil
+		}
+	}
+	b.nodeCount, b.nodeUsed = 0, 0
+	b.slotCount, b.slotUsed = 0, 0
+}
+
+// chunkSize returns the number of nodes, or slots, in a chunk.
+func (b *Builder) chunkSize() int {
+	if b.size == 0 {
+		return DefaultChunkSize
+	}
+	return b.size
+}
+
+// alloc returns a zeroed node from the current chunk.
+func (b *Builder) alloc() *Node {
+	size := b.chunkSize()
+	if b.nodeCount == 0 || b.nodeUsed == size {
+		if b.nodeCount == len(b.nodes) {
+			b.nodes = append(b.nodes, make([]Node, size))
+		}
+		b.nodeCount++
+		b.nodeUsed = 0
+	}
+
+	nd := &b.nodes[b.nodeCount-1][b.nodeUsed]
+	b.nodeUsed++
+	return nd
+}
+
+// slice returns a slice of n slots. Its capacity is n, so appending to it
+// never overwrites slots of other slices.
+func (b *Builder) slice(n int) []*Node {
+	size := b.chunkSize()
+	if n > size {
+		return make([]*Node, n)
+	}
+	if b.slotCount == 0 || b.slotUsed+n > size {
+		if b.slotCount == len(b.slots) {
+			b.slots = append(b.slots, make([]*Node, size))
+		}
+		b.slotCount++
+		b.slotUsed = 0
+	}
+
+	s := b.slots[b.slotCount-1][b.slotUsed : b.slotUsed+n : b.slotUsed+n]
+	b.slotUsed += n
+	return s
+}
+
+// setSiblings stores a copy of siblings as nd's siblings. The parents of the
+// siblings must already be set to nd.
+func (b *Builder) setSiblings(nd *Node, siblings []*Node) {
+	if len(siblings) == 0 {
+		return
+	}
+	nd.siblings = b.slice(len(siblings))
+	copy(nd.siblings, siblings)
+	nd.renumber(0)
+}
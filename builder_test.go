@@ -0,0 +1,183 @@
+package otree
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBuilderNode(t *testing.T) {
+	b := NewBuilder(4)
+	leaves := []*Node{b.New("a"), b.New("b"), b.New("c")}
+	s0, err := b.Node("s0", leaves...)
+	if err != nil {
+		t.Fatalf("Node() returns error %q, should be nil", err.Error())
+	}
+	root, err := b.Node("root", s0, b.New("s1"), b.New("s2"))
+	if err != nil {
+		t.Fatalf("Node() returns error %q, should be nil", err.Error())
+	}
+
+	want := "root[s0[a b c] s1 s2]"
+	if got := root.String(); got != want {
+		t.Errorf("Node() results in %q, should be %q", got, want)
+	}
+	for i, nd := range leaves {
+		if idx, err := nd.Index(); err != nil || idx != i || nd.parent != s0 {
+			t.Errorf("Index() returns %d, %v, should be %d", idx, err, i)
+		}
+	}
+
+	// appending must not overwrite the slots of the next slice
+	s0.Link(AtEnd, New("d"))
+	want = "root[s0[a b c d] s1 s2]"
+	if got := root.String(); got != want {
+		t.Errorf("Link() results in %q, should be %q", got, want)
+	}
+
+	// more siblings than fit in a chunk
+	many := make([]*Node, 10)
+	for i := range many {
+		many[i] = b.New(i)
+	}
+	nd, _ := b.Node("many", many...)
+	if got := nd.Degree(); got != len(many) {
+		t.Errorf("Degree() returns %d, should be %d", got, len(many))
+	}
+}
+
+func TestBuilderNodeErrors(t *testing.T) {
+	var b Builder
+	a, c := b.New("a"), b.New("c")
+	p, _ := b.Node("p", c)
+
+	tests := []struct {
+		siblings []*Node
+		err      error
+	}{
+		{[]*Node{a, c}, ErrNodeHasParent},
+		{[]*Node{a, a}, ErrDuplicateNodeFound},
+	}
+	for i, tst := range tests {
+		if _, err := b.Node("x", tst.siblings...); err != tst.err {
+			t.Errorf("%d: Node() returns error %v, should be %q", i, err, tst.err)
+		}
+		if a.parent != nil || c.parent != p {
+			t.Errorf("%d: Node() changes parents when failing", i)
+		}
+	}
+}
+
+func TestBuilderReset(t *testing.T) {
+	b := NewBuilder(0)
+	build := func() *Node {
+		root := b.New(nil)
+		for i := 0; i < 100; i++ {
+			nd, _ := b.Node(nil, b.New(nil), b.New(nil))
+			root.adopt(nd)
+		}
+		return root
+	}
+	build()
+	b.Reset()
+
+	allocs := testing.AllocsPerRun(10, func() {
+		b.Reset()
+		nd, _ := b.Node(nil, b.New(nil), b.New(nil), b.New(nil))
+		b.Node(nil, nd, b.New(nil))
+	})
+	if allocs != 0 {
+		t.Errorf("building after Reset() needs %.0f allocations, should be 0", allocs)
+	}
+
+	b.Reset()
+	if got := b.New("x"); got.parent != nil || got.Degree() != 0 {
+		t.Errorf("New() after Reset() returns a used node")
+	}
+	if got, want := Size(build(), true), 301; got != want {
+		t.Errorf("Size() after Reset() returns %d, should be %d", got, want)
+	}
+}
+
+func TestBuilderEvents(t *testing.T) {
+	var b Builder
+	b.Begin("root")
+	b.Begin("s0")
+	b.Leaf("a")
+	b.Begin("b")
+	b.End()
+	b.End()
+	b.Leaf("s1")
+	b.End()
+	root, err := b.Result()
+	if err != nil {
+		t.Fatalf("Result() returns error %q, should be nil", err.Error())
+	}
+	want := "root[s0[a b] s1]"
+	if got := root.String(); got != want {
+		t.Errorf("Result() returns %q, should be %q", got, want)
+	}
+
+	var events []string
+	f := func(e Event) error {
+		events = append(events, fmt.Sprintf("%v:%v", e.Kind, e.Data))
+		return nil
+	}
+	if err := Events(root, f); err != nil {
+		t.Errorf("Events() returns error %q, should be nil", err.Error())
+	}
+	wantEvents := "[Begin:root Begin:s0 Leaf:a Leaf:b End:<nil> Leaf:s1 End:<nil>]"
+	if got := fmt.Sprint(events); got != wantEvents {
+		t.Errorf("Events() results in %s, should be %s", got, wantEvents)
+	}
+
+	if err := Events(root, b.Emit); err != nil {
+		t.Errorf("Events() returns error %q, should be nil", err.Error())
+	}
+	cp, err := b.Result()
+	if err != nil || cp == root || cp.String() != want {
+		t.Errorf("Result() returns %v, %v, should be a copy %q", cp, err, want)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = Events(root, func(e Event) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Errorf("Events() returns error %v after %d events, should be %q after 3", err, n, stop)
+	}
+}
+
+func TestBuilderEventErrors(t *testing.T) {
+	tests := []struct {
+		events []Event
+		err    error
+	}{
+		{[]Event{{EventEnd, nil}}, ErrUnbalanced},
+		{[]Event{{EventBegin, 1}}, ErrUnbalanced},
+		{[]Event{{EventBegin, 1}, {EventEnd, nil}, {EventEnd, nil}, {EventBegin, 2}}, ErrUnbalanced},
+		{[]Event{}, ErrNoSingleRoot},
+		{[]Event{{EventLeaf, 1}, {EventLeaf, 2}}, ErrNoSingleRoot},
+		{[]Event{{EventBegin, 1}, {EventLeaf, 2}, {EventEnd, nil}}, nil},
+	}
+
+	b := NewBuilder(2)
+	for i, tst := range tests {
+		for _, e := range tst.events {
+			b.Emit(e)
+		}
+		if _, err := b.Result(); err != tst.err {
+			t.Errorf("%d: Result() returns error %v, should be %v", i, err, tst.err)
+		}
+	}
+
+	// an error is forgotten after Result
+	b.Leaf(1)
+	if root, err := b.Result(); err != nil || root.String() != "1" {
+		t.Errorf("Result() returns %v, %v, should be 1", root, err)
+	}
+}
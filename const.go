@@ -11,11 +11,22 @@ const (
 
 // Error codes
 var (
+	ErrAggregatesDisabled      = errors.New("otree: aggregates not enabled")
 	ErrCannotRemoveRootNode    = errors.New("otree: cannot remove root node")
 	ErrCannotReplaceRootNode   = errors.New("otree: cannot replace root node")
+	ErrDuplicateKey            = errors.New("otree: duplicate key")
 	ErrDuplicateNodeFound      = errors.New("otree: duplicate node found")
+	ErrInvalidMove             = errors.New("otree: cannot move node into its own subtree")
+	ErrNoKeyFunc               = errors.New("otree: tree has no key function")
+	ErrNodeHasParent           = errors.New("otree: node already has a parent")
 	ErrNodeMustNotHaveSiblings = errors.New("otree: node must not have siblings")
 	ErrNodeNotFound            = errors.New("otree: node not found")
+	ErrNoSingleRoot            = errors.New("otree: events don't build a single tree")
 	ErrNodesNotInSameTree      = errors.New("otree: nodes not in same tree")
+	ErrNothingToRedo           = errors.New("otree: nothing to redo")
+	ErrNothingToUndo           = errors.New("otree: nothing to undo")
 	ErrParentMissing           = errors.New("otree: parent missing")
+	ErrUnbalanced              = errors.New("otree: unbalanced begin and end")
+	ErrUnknownAggregate        = errors.New("otree: unknown aggregate")
+	ErrUnknownCheckpoint       = errors.New("otree: unknown checkpoint")
 )
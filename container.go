@@ -0,0 +1,303 @@
+package otree
+
+// ChangeKind identifies the kind of a change of a tree.
+type ChangeKind int
+
+const (
+	Linked      ChangeKind = iota // nodes are linked to a parent
+	Unlinked                      // nodes are removed from a parent
+	Replaced                      // a sibling is replaced by other nodes
+	DataChanged                   // the data of a node is changed
+)
+
+// String returns the name of k.
+func (k ChangeKind) String() string {
+	switch k {
+	case Linked:
+		return "Linked"
+	case Unlinked:
+		return "Unlinked"
+	case Replaced:
+		return "Replaced"
+	case DataChanged:
+		return "DataChanged"
+	}
+	return "Unknown"
+}
+
+// Change describes a change of a tree.
+type Change struct {
+	Kind    ChangeKind  // kind of change
+	Parent  *Node       // node whose siblings are changed, or the parent of the changed node
+	Index   int         // index of the first changed sibling, or of the changed node, -1 for the root
+	Nodes   []*Node     // linked, unlinked or replacing nodes, or the changed node
+	Old     *Node       // replaced node, only for Replaced
+	OldData interface{} // previous data, only for DataChanged
+	NewData interface{} // new data, only for DataChanged
+}
+
+// Listener is the interface implemented by types that want to be notified
+// about changes of a Tree. BeforeChange is called before a change is applied,
+// when a non nil error is returned the change is vetoed and the error is
+// returned by the Tree method performing the change. AfterChange is called
+// after the change is applied. Listeners must not change the tree.
+type Listener interface {
+	BeforeChange(c *Change) error
+	AfterChange(c *Change)
+}
+
+// ListenerFuncs is a Listener that calls its functions. Nil functions are
+// ignored.
+type ListenerFuncs struct {
+	Before func(c *Change) error
+	After  func(c *Change)
+}
+
+// BeforeChange implements the Listener interface.
+func (lf ListenerFuncs) BeforeChange(c *Change) error {
+	if lf.Before != nil {
+		return lf.Before(c)
+	}
+	return nil
+}
+
+// AfterChange implements the Listener interface.
+func (lf ListenerFuncs) AfterChange(c *Change) {
+	if lf.After != nil {
+		lf.After(c)
+	}
+}
+
+// Tree owns a tree of nodes. Changes made through its methods are reported to
+// its listeners. Changes made directly to its nodes are not.
+type Tree struct {
+	root      *Node
+	listeners []*listenerEntry
+	index     keyIndexer // nil when the tree has no key function
+}
+
+// listenerEntry holds a registered listener. Its address identifies the
+// registration.
+type listenerEntry struct {
+	l Listener
+}
+
+// NewTree returns a Tree that owns the tree starting at the root of node.
+func NewTree(node *Node) *Tree {
+	return &Tree{root: node.Root()}
+}
+
+// Root returns the root of t.
+func (t *Tree) Root() *Node {
+	return t.root
+}
+
+// Breadth returns the breadth of t.
+func (t *Tree) Breadth() int {
+	return Breadth(t.root, false)
+}
+
+// Degree returns the degree of t.
+func (t *Tree) Degree() int {
+	return Degree(t.root, false)
+}
+
+// Height returns the height of t.
+func (t *Tree) Height() int {
+	return Height(t.root, false)
+}
+
+// Size returns the size of t.
+func (t *Tree) Size() int {
+	return Size(t.root, false)
+}
+
+// Stats returns the statistics of t.
+func (t *Tree) Stats() TreeStats {
+	return Stats(t.root, false)
+}
+
+// String returns the string representation of t.
+func (t *Tree) String() string {
+	return t.root.String()
+}
+
+// Width returns the width of t for level.
+func (t *Tree) Width(level int) int {
+	return Width(t.root, level, false)
+}
+
+// AddListener adds l to t's listeners. Listeners are called in the order in
+// which they were added. The returned function removes l again.
+func (t *Tree) AddListener(l Listener) (remove func()) {
+	e := &listenerEntry{l}
+	t.listeners = append(t.listeners, e)
+	return func() {
+		for i, le := range t.listeners {
+			if le == e {
+				t.listeners = append(t.listeners[:i:i], t.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Link links nodes to parent, like Node.Link(). If parent isn't part of t
+// ErrNodesNotInSameTree will be returned.
+func (t *Tree) Link(parent *Node, index int, nodes ...*Node) error {
+	if err := t.contains(parent); err != nil {
+		return err
+	}
+	if err := parent.checkLink(nodes); err != nil {
+		return err
+	}
+
+	if d := parent.Degree(); index > d {
+		index = d
+	} else if index < 0 {
+		index = 0
+	}
+	c := &Change{Kind: Linked, Parent: parent, Index: index, Nodes: nodes}
+	return t.apply(c, func() {
+		parent.link(index, nodes)
+	})
+}
+
+// Remove removes nd from t, like Node.Remove().
+func (t *Tree) Remove(nd *Node) error {
+	if err := t.contains(nd); err != nil {
+		return err
+	}
+	if nd.parent == nil {
+		return ErrCannotRemoveRootNode
+	}
+	i, err := nd.Index()
+	if err != nil {
+		return err
+	}
+	_, err = t.RemoveSibling(nd.parent, i)
+	return err
+}
+
+// RemoveSibling removes parent's child with index, like
+// Node.RemoveSibling().
+func (t *Tree) RemoveSibling(parent *Node, index int) (*Node, error) {
+	if err := t.contains(parent); err != nil {
+		return nil, err
+	}
+	nd, err := parent.Sibling(index)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Change{Kind: Unlinked, Parent: parent, Index: index, Nodes: []*Node{nd}}
+	return nd, t.apply(c, func() {
+		parent.RemoveSibling(index)
+	})
+}
+
+// RemoveAllSiblings removes all of parent's siblings, like
+// Node.RemoveAllSiblings().
+func (t *Tree) RemoveAllSiblings(parent *Node) ([]*Node, error) {
+	if err := t.contains(parent); err != nil {
+		return nil, err
+	}
+	if parent.IsLeaf() {
+		return []*Node{}, nil
+	}
+
+	nodes := append([]*Node{}, parent.Siblings()...)
+	c := &Change{Kind: Unlinked, Parent: parent, Index: 0, Nodes: nodes}
+	if err := t.apply(c, func() {
+		parent.RemoveAllSiblings()
+	}); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Replace replaces nd by nodes, like Node.Replace().
+func (t *Tree) Replace(nd *Node, nodes ...*Node) error {
+	if err := t.contains(nd); err != nil {
+		return err
+	}
+	if nd.parent == nil {
+		return ErrCannotReplaceRootNode
+	}
+	i, err := nd.Index()
+	if err != nil {
+		return err
+	}
+	_, err = t.ReplaceSibling(nd.parent, i, nodes...)
+	return err
+}
+
+// ReplaceSibling replaces parent's child with index by nodes, like
+// Node.ReplaceSibling(). In contrast to Node.ReplaceSibling() the tree is not
+// changed when an error is returned.
+func (t *Tree) ReplaceSibling(parent *Node, index int, nodes ...*Node) (*Node, error) {
+	if err := t.contains(parent); err != nil {
+		return nil, err
+	}
+	nd, err := parent.Sibling(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.checkLink(nodes); err != nil {
+		return nil, err
+	}
+
+	c := &Change{Kind: Replaced, Parent: parent, Index: index, Nodes: nodes, Old: nd}
+	return nd, t.apply(c, func() {
+		parent.RemoveSibling(index)
+		parent.link(index, nodes)
+	})
+}
+
+// SetData sets the data of nd.
+func (t *Tree) SetData(nd *Node, data interface{}) error {
+	if err := t.contains(nd); err != nil {
+		return err
+	}
+
+	index := -1
+	if nd.parent != nil {
+		var err error
+		if index, err = nd.Index(); err != nil {
+			return err
+		}
+	}
+	c := &Change{Kind: DataChanged, Parent: nd.parent, Index: index,
+		Nodes: []*Node{nd}, OldData: nd.Data, NewData: data}
+	return t.apply(c, func() {
+		nd.Data = data
+		if nd.agg != nil {
+			nd.childrenChanged()
+		}
+	})
+}
+
+// apply asks t's listeners for permission to make change c, applies it by
+// calling f and reports it to the listeners.
+func (t *Tree) apply(c *Change, f func()) error {
+	listeners := append([]*listenerEntry{}, t.listeners...)
+	for _, e := range listeners {
+		if err := e.l.BeforeChange(c); err != nil {
+			return err
+		}
+	}
+	f()
+	for _, e := range listeners {
+		e.l.AfterChange(c)
+	}
+	return nil
+}
+
+// contains checks if nd is part of t. If not, ErrNodesNotInSameTree will be
+// returned.
+func (t *Tree) contains(nd *Node) error {
+	if nd.Root() != t.root {
+		return ErrNodesNotInSameTree
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package otree
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// recorder is a Listener that records the changes it is notified about.
+type recorder struct {
+	before, after []string
+	veto          error
+}
+
+func (r *recorder) BeforeChange(c *Change) error {
+	r.before = append(r.before, changeString(c))
+	return r.veto
+}
+
+func (r *recorder) AfterChange(c *Change) {
+	r.after = append(r.after, changeString(c))
+}
+
+func changeString(c *Change) string {
+	s := c.Kind.String()
+	if c.Parent != nil {
+		s += fmt.Sprintf(" %v:%d", c.Parent.Data, c.Index)
+	}
+	s += " " + nodesString(c.Nodes)
+	if c.Old != nil {
+		s += fmt.Sprintf(" old %v", c.Old.Data)
+	}
+	if c.Kind == DataChanged {
+		s += fmt.Sprintf(" %v->%v", c.OldData, c.NewData)
+	}
+	return s
+}
+
+func TestTreeListeners(t *testing.T) {
+	root := New("root")
+	s0, s1, s2 := New("s0"), New("s1"), New("s2")
+	tr := NewTree(root)
+	r := &recorder{}
+	tr.AddListener(r)
+
+	tests := []struct {
+		op     func() error
+		change string
+		want   string
+	}{
+		{func() error { return tr.Link(root, AtEnd, s0, s1) }, "Linked root:0 [s0 s1]", "root[s0 s1]"},
+		{func() error { return tr.Link(root, 1, s2) }, "Linked root:1 [s2]", "root[s0 s2 s1]"},
+		{func() error { return tr.Remove(s2) }, "Unlinked root:1 [s2@root]", "root[s0 s1]"},
+		{func() error { _, err := tr.RemoveSibling(root, 0); return err }, "Unlinked root:0 [s0@root]", "root[s1]"},
+		{func() error { return tr.Replace(s1, s0, s2) }, "Replaced root:0 [s0 s2] old s1", "root[s0 s2]"},
+		{func() error { return tr.SetData(s2, "x") }, "DataChanged root:1 [s2@root] s2->x", "root[s0 x]"},
+		{func() error { _, err := tr.RemoveAllSiblings(root); return err }, "Unlinked root:0 [s0@root x@root]", "root"},
+		{func() error { return tr.SetData(root, "top") }, "DataChanged [root] root->top", "top"},
+	}
+
+	for i, tst := range tests {
+		r.before, r.after = nil, nil
+		if err := tst.op(); err != nil {
+			t.Errorf("%d: returns error %q, should be nil", i, err.Error())
+			continue
+		}
+		if len(r.before) != 1 || r.before[0] != tst.change {
+			t.Errorf("%d: BeforeChange() is called with %q, should be %q", i, r.before, tst.change)
+		}
+		if len(r.after) != 1 {
+			t.Errorf("%d: AfterChange() is called %d times, should be 1", i, len(r.after))
+		}
+		if got := root.String(); got != tst.want {
+			t.Errorf("%d: tree is %q, should be %q", i, got, tst.want)
+		}
+	}
+}
+
+func TestTreeVeto(t *testing.T) {
+	root := New("root")
+	s0 := New("s0")
+	root.Link(AtEnd, s0)
+	tr := NewTree(root)
+
+	errVeto := errors.New("veto")
+	r := &recorder{veto: errVeto}
+	remove := tr.AddListener(r)
+	after := 0
+	tr.AddListener(ListenerFuncs{After: func(c *Change) { after++ }})
+
+	ops := []func() error{
+		func() error { return tr.Link(root, AtEnd, New("s1")) },
+		func() error { return tr.Remove(s0) },
+		func() error { return tr.Replace(s0, New("s1")) },
+		func() error { return tr.SetData(s0, "x") },
+		func() error { _, err := tr.RemoveAllSiblings(root); return err },
+	}
+	for i, op := range ops {
+		if err := op(); err != errVeto {
+			t.Errorf("%d: returns error %v, should be %q", i, err, errVeto)
+		}
+	}
+	if got := root.String(); got != "root[s0]" || after != 0 || len(r.after) != 0 {
+		t.Errorf("vetoed changes result in %q with %d notifications", got, after+len(r.after))
+	}
+
+	remove()
+	if err := tr.SetData(s0, "x"); err != nil || after != 1 {
+		t.Errorf("SetData() after removing the vetoing listener returns %v", err)
+	}
+}
+
+func TestTreeErrors(t *testing.T) {
+	root := New("root")
+	s0 := New("s0")
+	tr := NewTree(root)
+	tr.Link(root, AtEnd, s0)
+	r := &recorder{}
+	tr.AddListener(r)
+
+	other := New("other")
+	tests := []struct {
+		op  func() error
+		err error
+	}{
+		{func() error { return tr.Link(other, AtEnd, New("x")) }, ErrNodesNotInSameTree},
+		{func() error { return tr.Link(root, AtEnd, s0) }, ErrDuplicateNodeFound},
+		{func() error { return tr.Remove(root) }, ErrCannotRemoveRootNode},
+		{func() error { return tr.Replace(root, New("x")) }, ErrCannotReplaceRootNode},
+		{func() error { _, err := tr.RemoveSibling(root, 1); return err }, ErrNodeNotFound},
+		{func() error { _, err := tr.ReplaceSibling(root, 0, root); return err }, ErrDuplicateNodeFound},
+		{func() error { return tr.SetData(other, "x") }, ErrNodesNotInSameTree},
+	}
+	for i, tst := range tests {
+		if err := tst.op(); err != tst.err {
+			t.Errorf("%d: returns error %v, should be %q", i, err, tst.err)
+		}
+	}
+	if len(r.before) != 0 || root.String() != "root[s0]" {
+		t.Errorf("failing changes result in %q with notifications %q", root.String(), r.before)
+	}
+}
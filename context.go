@@ -0,0 +1,103 @@
+package otree
+
+import "context"
+
+// checkInterval is the number of nodes visited between two checks whether a
+// context is done.
+const checkInterval = 1024
+
+// WalkContext is like root.Walk(f, data), but it checks every so many nodes
+// whether ctx is done. If so it stops and returns ctx.Err().
+func WalkContext(ctx context.Context, root *Node, f WalkFunc, data interface{}) error {
+	return walkContext(ctx, root, func(nd *Node, level int) bool {
+		f(nd, data)
+		return true
+	})
+}
+
+// FindContext is like root.Find(f), but it checks every so many nodes whether
+// ctx is done. If so it stops and returns ctx.Err().
+func FindContext(ctx context.Context, root *Node, f func(node *Node) bool) (*Node, error) {
+	var found *Node
+	err := walkContext(ctx, root, func(nd *Node, level int) bool {
+		if f(nd) {
+			found = nd
+			return false
+		}
+		return true
+	})
+
+	switch {
+	case err != nil:
+		return nil, err
+	case found == nil:
+		return nil, ErrNodeNotFound
+	}
+	return found, nil
+}
+
+// HeightContext is like Height(node, sub), but it checks every so many nodes
+// whether ctx is done. If so it stops and returns ctx.Err().
+func HeightContext(ctx context.Context, node *Node, sub bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	root := selectRoot(node, sub)
+	if root.agg != nil {
+		return root.agg.height, nil
+	}
+
+	height := 0
+	err := walkContext(ctx, root, func(nd *Node, level int) bool {
+		if level > height {
+			height = level
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+// SizeContext is like Size(node, sub), but it checks every so many nodes
+// whether ctx is done. If so it stops and returns ctx.Err().
+func SizeContext(ctx context.Context, node *Node, sub bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	root := selectRoot(node, sub)
+	if root.agg != nil {
+		return root.agg.size, nil
+	}
+
+	n := 0
+	err := walkContext(ctx, root, func(nd *Node, level int) bool {
+		n++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// walkContext calls f like nd.walk(f) does, but it stops when ctx is done and
+// returns ctx.Err().
+func walkContext(ctx context.Context, nd *Node, f func(node *Node, level int) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	n := 0
+	nd.walk(func(node *Node, level int) bool {
+		if n++; n%checkInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+		}
+		return f(node, level)
+	})
+	return err
+}
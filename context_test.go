@@ -0,0 +1,69 @@
+package otree
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextFunctions(t *testing.T) {
+	root := transformTree()
+	ctx := context.Background()
+
+	n := 0
+	if err := WalkContext(ctx, root, func(nd *Node, data interface{}) { n += data.(int) }, 2); err != nil || n != 14 {
+		t.Errorf("WalkContext() returns %d, %v, should be 14, nil", n, err)
+	}
+	if nd, err := FindContext(ctx, root, func(nd *Node) bool { return nd.Data == 4 }); err != nil || nd.Data != 4 {
+		t.Errorf("FindContext() returns %v, %v, should be 4, nil", nd, err)
+	}
+	if _, err := FindContext(ctx, root, func(nd *Node) bool { return false }); err != ErrNodeNotFound {
+		t.Errorf("FindContext() returns error %v, should be %q", err, ErrNodeNotFound)
+	}
+	if h, err := HeightContext(ctx, root, false); err != nil || h != 3 {
+		t.Errorf("HeightContext() returns %d, %v, should be 3, nil", h, err)
+	}
+	if s, err := SizeContext(ctx, root.siblings[1], true); err != nil || s != 2 {
+		t.Errorf("SizeContext() returns %d, %v, should be 2, nil", s, err)
+	}
+
+	EnableAggregates(root)
+	if h, err := HeightContext(ctx, root, false); err != nil || h != 3 {
+		t.Errorf("HeightContext() with aggregates returns %d, %v, should be 3, nil", h, err)
+	}
+	if s, err := SizeContext(ctx, root, false); err != nil || s != 7 {
+		t.Errorf("SizeContext() with aggregates returns %d, %v, should be 7, nil", s, err)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	root := wideTree(100)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n := 0
+	err := WalkContext(ctx, root, func(nd *Node, data interface{}) {
+		if n++; n == 10 {
+			cancel()
+		}
+	}, nil)
+	if err != context.Canceled || n >= Size(root, false) || n > 10+checkInterval {
+		t.Errorf("WalkContext() returns error %v after %d nodes, should be %q", err, n, context.Canceled)
+	}
+
+	if _, err := FindContext(ctx, root, func(nd *Node) bool { return true }); err != context.Canceled {
+		t.Errorf("FindContext() returns error %v, should be %q", err, context.Canceled)
+	}
+	if _, err := HeightContext(ctx, root, false); err != context.Canceled {
+		t.Errorf("HeightContext() returns error %v, should be %q", err, context.Canceled)
+	}
+	if _, err := SizeContext(ctx, root, false); err != context.Canceled {
+		t.Errorf("SizeContext() returns error %v, should be %q", err, context.Canceled)
+	}
+
+	EnableAggregates(root)
+	if h, err := HeightContext(ctx, root, false); err != context.Canceled || h != 0 {
+		t.Errorf("HeightContext() with aggregates returns %d, %v, should be 0, %q", h, err, context.Canceled)
+	}
+	if s, err := SizeContext(ctx, root, false); err != context.Canceled || s != 0 {
+		t.Errorf("SizeContext() with aggregates returns %d, %v, should be 0, %q", s, err, context.Canceled)
+	}
+}
@@ -0,0 +1,215 @@
+package otree
+
+// Cursor points to a node in a tree. It remembers the index of that node and
+// of each of its ancestors, so it can move through the tree without searching
+// the lists of siblings. Moving methods return false, and leave the cursor
+// unchanged, when the move is not possible.
+// A cursor stays valid when the tree is modified through the cursor. When the
+// tree is modified otherwise the indexes are recomputed when needed.
+type Cursor struct {
+	node    *Node
+	indexes []int // indexes of the ancestors below the root and of node
+}
+
+// NewCursor returns a cursor pointing to nd.
+func NewCursor(nd *Node) *Cursor {
+	c := &Cursor{node: nd}
+	c.resync()
+	return c
+}
+
+// Node returns the node to which c points.
+func (c *Cursor) Node() *Node {
+	return c.node
+}
+
+// Index returns the index of c's node in its parent's list of siblings. For
+// the root node it returns -1.
+func (c *Cursor) Index() int {
+	if !c.sync(true) {
+		return -1
+	}
+	return c.indexes[len(c.indexes)-1]
+}
+
+// Up moves c to the parent of its node.
+func (c *Cursor) Up() bool {
+	if !c.sync(false) {
+		return false
+	}
+	c.node = c.node.parent
+	c.indexes = c.indexes[:len(c.indexes)-1]
+	return true
+}
+
+// Down moves c to the child of its node with index.
+func (c *Cursor) Down(index int) bool {
+	sbl, err := c.node.Sibling(index)
+	if err != nil {
+		return false
+	}
+	c.sync(false)
+	c.node = sbl
+	c.indexes = append(c.indexes, index)
+	return true
+}
+
+// FirstChild moves c to the first child of its node.
+func (c *Cursor) FirstChild() bool {
+	return c.Down(0)
+}
+
+// LastChild moves c to the last child of its node.
+func (c *Cursor) LastChild() bool {
+	return c.Down(c.node.Degree() - 1)
+}
+
+// Next moves c to the next sibling of its node.
+func (c *Cursor) Next() bool {
+	return c.sideways(1)
+}
+
+// Prev moves c to the previous sibling of its node.
+func (c *Cursor) Prev() bool {
+	return c.sideways(-1)
+}
+
+// Root moves c to the root of the tree. It returns false if c already points
+// to the root.
+func (c *Cursor) Root() bool {
+	if c.node.parent == nil {
+		return false
+	}
+	c.node = c.node.Root()
+	c.indexes = c.indexes[:0]
+	return true
+}
+
+// InsertBefore links nodes to the parent of c's node, just before that node.
+// c keeps pointing to the same node. If c points to the root node
+// ErrParentMissing will be returned.
+func (c *Cursor) InsertBefore(nodes ...*Node) error {
+	return c.insert(0, nodes)
+}
+
+// InsertAfter links nodes to the parent of c's node, just after that node. c
+// keeps pointing to the same node. If c points to the root node
+// ErrParentMissing will be returned.
+func (c *Cursor) InsertAfter(nodes ...*Node) error {
+	return c.insert(1, nodes)
+}
+
+// Replace replaces c's node by nd and moves c to nd. If c points to the root
+// node ErrCannotReplaceRootNode will be returned.
+func (c *Cursor) Replace(nd *Node) error {
+	if !c.sync(true) {
+		return ErrCannotReplaceRootNode
+	}
+	last := len(c.indexes) - 1
+	if _, err := c.node.parent.ReplaceSibling(c.indexes[last], nd); err != nil {
+		return err
+	}
+	c.node = nd
+	return nil
+}
+
+// Delete removes c's node from the tree and returns it. c moves to the next
+// sibling, or if there is none to the previous sibling, or if there is none
+// to the parent. If c points to the root node ErrCannotRemoveRootNode will be
+// returned.
+func (c *Cursor) Delete() (*Node, error) {
+	if !c.sync(true) {
+		return nil, ErrCannotRemoveRootNode
+	}
+	last := len(c.indexes) - 1
+	i := c.indexes[last]
+	p := c.node.parent
+	nd, err := p.RemoveSibling(i)
+	if err != nil {
+		return nil, err
+	}
+
+	switch d := p.Degree(); {
+	case i < d:
+		c.node, _ = p.Sibling(i)
+	case d > 0:
+		c.node, _ = p.Sibling(d - 1)
+		c.indexes[last] = d - 1
+	default:
+		c.node = p
+		c.indexes = c.indexes[:last]
+	}
+	return nd, nil
+}
+
+// insert links nodes to the parent of c's node at offset from the node.
+func (c *Cursor) insert(offset int, nodes []*Node) error {
+	if !c.sync(true) {
+		return ErrParentMissing
+	}
+	last := len(c.indexes) - 1
+	if err := c.node.parent.Link(c.indexes[last]+offset, nodes...); err != nil {
+		return err
+	}
+	if offset == 0 {
+		c.indexes[last] += len(nodes)
+	}
+	return nil
+}
+
+// sideways moves c one position forwards, if d is 1, or backwards, if d is
+// -1, in the list of siblings.
+func (c *Cursor) sideways(d int) bool {
+	if !c.sync(false) {
+		return false
+	}
+	last := len(c.indexes) - 1
+	p := c.node.parent
+	var sbl *Node
+	if p.list != nil {
+		if sbl = c.node.next; d < 0 {
+			sbl = c.node.prev
+		}
+	} else {
+		sbl, _ = p.Sibling(c.indexes[last] + d)
+	}
+	if sbl == nil {
+		return false
+	}
+	c.node = sbl
+	c.indexes[last] += d
+	return true
+}
+
+// sync checks if the remembered index of c's node is still valid and
+// recomputes the indexes if not. The indexes of the ancestors are checked
+// when c moves up to them. When the parent keeps its siblings in a list,
+// checking the index takes O(index) time, so then only the parent is checked
+// unless exact is true. It returns false if c points to a root node.
+func (c *Cursor) sync(exact bool) bool {
+	l := len(c.indexes)
+	p := c.node.parent
+	switch {
+	case p == nil && l == 0:
+		return false
+	case p != nil && l > 0:
+		if p.list != nil && !exact {
+			return true
+		}
+		if sbl, err := p.Sibling(c.indexes[l-1]); err == nil && sbl == c.node {
+			return true
+		}
+	}
+	c.resync()
+	return c.node.parent != nil
+}
+
+// resync recomputes the indexes of c's node and its ancestors.
+func (c *Cursor) resync() {
+	c.indexes = c.indexes[:0]
+	for nd := c.node; nd.parent != nil; nd = nd.parent {
+		i, _ := nd.Index()
+		c.indexes = append(c.indexes, i)
+	}
+	invertInts(c.indexes)
+}
@@ -0,0 +1,137 @@
+package otree
+
+import (
+	"testing"
+)
+
+func TestCursorNavigation(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := transformTree() // 0[1[3 4[6]] 2[5]]
+		if list {
+			root.Walk(func(nd *Node, data interface{}) { nd.SetListStorage(true) }, nil)
+		}
+		c := NewCursor(root)
+
+		tests := []struct {
+			move func() bool
+			ok   bool
+			data int
+			idx  int
+		}{
+			{c.Up, false, 0, -1},
+			{c.Next, false, 0, -1},
+			{c.Root, false, 0, -1},
+			{func() bool { return c.Down(2) }, false, 0, -1},
+			{c.FirstChild, true, 1, 0},
+			{c.Prev, false, 1, 0},
+			{c.LastChild, true, 4, 1},
+			{c.FirstChild, true, 6, 0},
+			{c.FirstChild, false, 6, 0},
+			{c.Up, true, 4, 1},
+			{c.Prev, true, 3, 0},
+			{c.Up, true, 1, 0},
+			{c.Next, true, 2, 1},
+			{c.Next, false, 2, 1},
+			{func() bool { return c.Down(0) }, true, 5, 0},
+			{c.Root, true, 0, -1},
+		}
+
+		for i, tst := range tests {
+			if ok := tst.move(); ok != tst.ok {
+				t.Errorf("list %t, %d: move returns %t, should be %t", list, i, ok, tst.ok)
+			}
+			if got := c.Node().Data; got != tst.data {
+				t.Errorf("list %t, %d: cursor points to %v, should be %d", list, i, got, tst.data)
+			}
+			if got := c.Index(); got != tst.idx {
+				t.Errorf("list %t, %d: Index() returns %d, should be %d", list, i, got, tst.idx)
+			}
+		}
+
+		c = NewCursor(root.Siblings()[0].Siblings()[1].Siblings()[0])
+		if !c.Up() || !c.Prev() || c.Node().Data != 3 {
+			t.Errorf("list %t: NewCursor() doesn't remember the indexes of the ancestors", list)
+		}
+	}
+}
+
+func TestCursorEdits(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := New("root")
+		root.SetListStorage(list)
+		s1 := New("s1")
+		root.Link(AtEnd, s1)
+		c := NewCursor(s1)
+
+		if err := c.InsertBefore(New("s0")); err != nil {
+			t.Errorf("list %t: InsertBefore() returns error %q, should be nil", list, err.Error())
+		}
+		if err := c.InsertAfter(New("s2"), New("s3")); err != nil {
+			t.Errorf("list %t: InsertAfter() returns error %q, should be nil", list, err.Error())
+		}
+		if c.Node() != s1 || c.Index() != 1 {
+			t.Errorf("list %t: the cursor points to %q with index %d, should be %q with index 1",
+				list, c.Node().String(), c.Index(), s1.String())
+		}
+		if err := c.InsertAfter(root); err != ErrDuplicateNodeFound {
+			t.Errorf("list %t: InsertAfter() returns error %v, should be %q", list, err, ErrDuplicateNodeFound)
+		}
+
+		sa := New("sa")
+		if err := c.Replace(sa); err != nil {
+			t.Errorf("list %t: Replace() returns error %q, should be nil", list, err.Error())
+		}
+		if c.Node() != sa || s1.parent != nil {
+			t.Errorf("list %t: Replace() doesn't move the cursor to the new node", list)
+		}
+		if got, want := root.String(), "root[s0 sa s2 s3]"; got != want {
+			t.Errorf("list %t: the tree is %q, should be %q", list, got, want)
+		}
+
+		// modify the tree without using the cursor
+		root.Link(AtStart, New("x"))
+		if !c.Next() || c.Node().Data != "s2" || c.Index() != 3 {
+			t.Errorf("list %t: Next() after an external change points to %q with index %d, should be %q with index 3",
+				list, c.Node().String(), c.Index(), "s2")
+		}
+
+		c.Next()
+		wants := []struct {
+			removed, tree, node string
+		}{
+			{"s3", "root[x s0 sa s2]", "s2"},
+			{"s2", "root[x s0 sa]", "sa"},
+		}
+		for _, w := range wants {
+			nd, err := c.Delete()
+			if err != nil {
+				t.Fatalf("list %t: Delete() returns error %q, should be nil", list, err.Error())
+			}
+			if nd.Data != w.removed || root.String() != w.tree || c.Node().Data != w.node {
+				t.Errorf("list %t: Delete() removes %q, results in %q and %q, should be %q, %q and %q",
+					list, nd.String(), root.String(), c.Node().String(), w.removed, w.tree, w.node)
+			}
+		}
+		c.Prev()
+		c.Prev()
+		c.Delete()
+		if c.Node().Data != "s0" || c.Index() != 0 {
+			t.Errorf("list %t: Delete() moves the cursor to %q, should be %q", list, c.Node().String(), "s0")
+		}
+		c.Delete()
+		c.Delete()
+		if c.Node() != root || root.String() != "root" {
+			t.Errorf("list %t: Delete() of the last sibling results in %q", list, c.Node().String())
+		}
+
+		if _, err := c.Delete(); err != ErrCannotRemoveRootNode {
+			t.Errorf("list %t: Delete() returns error %v, should be %q", list, err, ErrCannotRemoveRootNode)
+		}
+		if err := c.Replace(New("r")); err != ErrCannotReplaceRootNode {
+			t.Errorf("list %t: Replace() returns error %v, should be %q", list, err, ErrCannotReplaceRootNode)
+		}
+		if err := c.InsertBefore(New("r")); err != ErrParentMissing {
+			t.Errorf("list %t: InsertBefore() returns error %v, should be %q", list, err, ErrParentMissing)
+		}
+	}
+}
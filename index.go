@@ -0,0 +1,206 @@
+package otree
+
+// KeyFunc is a function type that returns the key of a node. If ok is false
+// the node is not indexed.
+type KeyFunc[K comparable] func(nd *Node) (key K, ok bool)
+
+// keyIndexer is implemented by the key indexes of all key types.
+type keyIndexer interface {
+	Listener
+	get(key interface{}) (*Node, bool)
+	reindex(root *Node) error
+}
+
+// keyIndex maps the keys of the nodes in a tree to these nodes.
+type keyIndex[K comparable] struct {
+	key   KeyFunc[K]
+	nodes map[K]*Node
+	keys  map[*Node]K
+}
+
+// NewIndexedTree returns a Tree that owns the tree starting at the root of
+// node and that maintains an index of its nodes by the keys returned by key.
+// The index is kept up to date for all changes made through the Tree. Changes
+// that would result in two nodes with the same key are refused with
+// ErrDuplicateKey. If the tree already contains duplicate keys
+// ErrDuplicateKey will be returned.
+func NewIndexedTree[K comparable](node *Node, key KeyFunc[K]) (*Tree, error) {
+	t := NewTree(node)
+	t.index = &keyIndex[K]{key: key}
+	if err := t.Reindex(); err != nil {
+		return nil, err
+	}
+	t.AddListener(t.index)
+	return t, nil
+}
+
+// Get returns the node with key. If there is no such node, or if key doesn't
+// have the type of the keys of t, ErrNodeNotFound will be returned. If t has
+// no key function ErrNoKeyFunc will be returned.
+func (t *Tree) Get(key interface{}) (*Node, error) {
+	if t.index == nil {
+		return nil, ErrNoKeyFunc
+	}
+	if nd, ok := t.index.get(key); ok {
+		return nd, nil
+	}
+	return nil, ErrNodeNotFound
+}
+
+// Has tells if t holds a node with key.
+func (t *Tree) Has(key interface{}) bool {
+	_, err := t.Get(key)
+	return err == nil
+}
+
+// Reindex rebuilds t's index. It must be called when the keys of nodes are
+// changed other than through t. If the tree contains duplicate keys
+// ErrDuplicateKey will be returned and the index is left unchanged. If t has
+// no key function ErrNoKeyFunc will be returned.
+func (t *Tree) Reindex() error {
+	if t.index == nil {
+		return ErrNoKeyFunc
+	}
+	return t.index.reindex(t.root)
+}
+
+// get returns the node with key.
+func (idx *keyIndex[K]) get(key interface{}) (*Node, bool) {
+	k, ok := key.(K)
+	if !ok {
+		return nil, false
+	}
+	nd, ok := idx.nodes[k]
+	return nd, ok
+}
+
+// reindex rebuilds the index for the tree starting at root.
+func (idx *keyIndex[K]) reindex(root *Node) error {
+	nodes := make(map[K]*Node)
+	if err := idx.collect(nodes, []*Node{root}, nil); err != nil {
+		return err
+	}
+	idx.nodes = nodes
+	idx.keys = make(map[*Node]K, len(nodes))
+	for k, nd := range nodes {
+		idx.keys[nd] = k
+	}
+	return nil
+}
+
+// BeforeChange implements the Listener interface. It refuses changes that
+// result in duplicate keys.
+func (idx *keyIndex[K]) BeforeChange(c *Change) error {
+	switch c.Kind {
+	case Linked:
+		return idx.collect(make(map[K]*Node), c.Nodes, nil)
+
+	case Replaced:
+		return idx.collect(make(map[K]*Node), c.Nodes, c.Old)
+
+	case DataChanged:
+		// the key is taken from a copy, so nd is left untouched
+		nd := c.Nodes[0]
+		tmp := *nd
+		tmp.Data = c.NewData
+		k, ok := idx.key(&tmp)
+		if other, found := idx.nodes[k]; ok && found && other != nd {
+			return ErrDuplicateKey
+		}
+	}
+	return nil
+}
+
+// AfterChange implements the Listener interface. It updates the index.
+func (idx *keyIndex[K]) AfterChange(c *Change) {
+	switch c.Kind {
+	case Linked:
+		idx.add(c.Nodes)
+
+	case Unlinked:
+		idx.remove(c.Nodes)
+
+	case Replaced:
+		idx.remove([]*Node{c.Old})
+		idx.add(c.Nodes)
+
+	case DataChanged:
+		idx.removeNode(c.Nodes[0], nil)
+		idx.addNode(c.Nodes[0], nil)
+	}
+}
+
+// collect adds the keys of the (sub)trees starting at nodes to found. If a
+// key is already in found, or in the index and not owned by a node in the
+// subtree leaving, ErrDuplicateKey will be returned.
+func (idx *keyIndex[K]) collect(found map[K]*Node, nodes []*Node, leaving *Node) error {
+	var err error
+	f := func(nd *Node, data interface{}) {
+		k, ok := idx.key(nd)
+		if err != nil || !ok {
+			return
+		}
+		if _, ok := found[k]; ok {
+			err = ErrDuplicateKey
+			return
+		}
+		if other, ok := idx.nodes[k]; ok && !isInSubtree(other, leaving) {
+			err = ErrDuplicateKey
+			return
+		}
+		found[k] = nd
+	}
+
+	for _, n := range nodes {
+		if n.Walk(f, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds the nodes of the (sub)trees starting at nodes to the index.
+func (idx *keyIndex[K]) add(nodes []*Node) {
+	for _, n := range nodes {
+		n.Walk(idx.addNode, nil)
+	}
+}
+
+// addNode is a WalkFunc that adds nd to the index.
+func (idx *keyIndex[K]) addNode(nd *Node, data interface{}) {
+	if k, ok := idx.key(nd); ok {
+		idx.nodes[k] = nd
+		idx.keys[nd] = k
+	}
+}
+
+// remove removes the nodes of the (sub)trees starting at nodes from the
+// index.
+func (idx *keyIndex[K]) remove(nodes []*Node) {
+	for _, n := range nodes {
+		n.Walk(idx.removeNode, nil)
+	}
+}
+
+// removeNode is a WalkFunc that removes nd from the index.
+func (idx *keyIndex[K]) removeNode(nd *Node, data interface{}) {
+	if k, ok := idx.keys[nd]; ok {
+		delete(idx.keys, nd)
+		if idx.nodes[k] == nd {
+			delete(idx.nodes, k)
+		}
+	}
+}
+
+// isInSubtree tells if nd is part of the subtree starting at root.
+func isInSubtree(nd, root *Node) bool {
+	if root == nil {
+		return false
+	}
+	for n := nd; n != nil; n = n.parent {
+		if n == root {
+			return true
+		}
+	}
+	return false
+}
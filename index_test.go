@@ -0,0 +1,126 @@
+package otree
+
+import (
+	"errors"
+	"testing"
+)
+
+func dataKey(nd *Node) (string, bool) {
+	s, ok := nd.Data.(string)
+	return s, ok && s != ""
+}
+
+func TestIndexedTree(t *testing.T) {
+	root := New("root")
+	a, b := New("a"), New(1)
+	root.Link(AtEnd, a, b)
+
+	tr, err := NewIndexedTree(a, dataKey)
+	if err != nil {
+		t.Fatalf("NewIndexedTree() returns error %q, should be nil", err.Error())
+	}
+	if nd, err := tr.Get("a"); err != nil || nd != a {
+		t.Errorf("Get(\"a\") returns %v, %v, should be %q, nil", nd, err, a.String())
+	}
+	if tr.Has(1) {
+		t.Errorf("Has(1) returns true for a node without a key")
+	}
+
+	sub := New("c")
+	sub.Link(AtEnd, New("d"), New("e"))
+	steps := []struct {
+		op      func() error
+		err     error
+		present []string
+		absent  []string
+	}{
+		{func() error { return tr.Link(b, AtEnd, sub) }, nil, []string{"c", "d", "e"}, nil},
+		{func() error { return tr.Link(root, AtEnd, New("d")) }, ErrDuplicateKey, []string{"d"}, nil},
+		{func() error { return tr.Link(root, AtEnd, New("x"), New("x")) }, ErrDuplicateKey, nil, []string{"x"}},
+		{func() error { return tr.Replace(sub, New("c"), New("f")) }, nil, []string{"c", "f"}, []string{"d", "e"}},
+		{func() error { return tr.Replace(a, New("f")) }, ErrDuplicateKey, []string{"a"}, nil},
+		{func() error { return tr.SetData(a, "g") }, nil, []string{"g"}, []string{"a"}},
+		{func() error { return tr.SetData(a, "f") }, ErrDuplicateKey, []string{"g"}, nil},
+		{func() error { return tr.Remove(b) }, nil, []string{"g"}, []string{"c", "f"}},
+		{func() error { _, err := tr.RemoveAllSiblings(root); return err }, nil, []string{"root"}, []string{"g"}},
+	}
+
+	for i, s := range steps {
+		if err := s.op(); err != s.err {
+			t.Errorf("%d: returns error %v, should be %v", i, err, s.err)
+		}
+		for _, k := range s.present {
+			if nd, err := tr.Get(k); err != nil || nd.Data != k || nd.Root() != root {
+				t.Errorf("%d: Get(%q) returns %v, %v", i, k, nd, err)
+			}
+		}
+		for _, k := range s.absent {
+			if _, err := tr.Get(k); err != ErrNodeNotFound {
+				t.Errorf("%d: Get(%q) returns error %v, should be %q", i, k, err, ErrNodeNotFound)
+			}
+		}
+	}
+
+	// change a key without using the tree
+	root.Data = "top"
+	if err := tr.Reindex(); err != nil || !tr.Has("top") || tr.Has("root") {
+		t.Errorf("Reindex() returns %v and doesn't update the index", err)
+	}
+}
+
+func TestIndexedTreeErrors(t *testing.T) {
+	root := New("root")
+	root.Link(AtEnd, New("a"), New("a"))
+	if _, err := NewIndexedTree(root, dataKey); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("NewIndexedTree() returns error %v, should be %q", err, ErrDuplicateKey)
+	}
+
+	// data that can't be a map key, indexed by its length
+	sliceKey := func(nd *Node) (int, bool) {
+		s, ok := nd.Data.([]int)
+		return len(s), ok
+	}
+	top := New([]int{1})
+	top.Link(AtEnd, New([]int{1, 2}), New("b"))
+	tr, err := NewIndexedTree(top, sliceKey)
+	if err != nil {
+		t.Fatalf("NewIndexedTree() returns error %q, should be nil", err.Error())
+	}
+	if !tr.Has(2) || tr.Has(3) || tr.Has("2") {
+		t.Errorf("Has() doesn't find the nodes by their int keys")
+	}
+	if err := tr.Link(top, AtEnd, New([]int{4, 5})); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Link() returns error %v, should be %q", err, ErrDuplicateKey)
+	}
+
+	// the key of the new data is computed without changing the node
+	x := New("x")
+	x.Link(AtEnd, New("y"))
+	seen := false
+	tr, _ = NewIndexedTree(x, func(nd *Node) (string, bool) {
+		seen = seen || x.Data == "y"
+		return dataKey(nd)
+	})
+	if err := tr.SetData(x, "y"); err != ErrDuplicateKey || seen {
+		t.Errorf("SetData() returns error %v and shows the refused data to the key function", err)
+	}
+
+	tr = NewTree(root)
+	if _, err := tr.Get("a"); err != ErrNoKeyFunc {
+		t.Errorf("Get() returns error %v, should be %q", err, ErrNoKeyFunc)
+	}
+	if err := tr.Reindex(); err != ErrNoKeyFunc {
+		t.Errorf("Reindex() returns error %v, should be %q", err, ErrNoKeyFunc)
+	}
+}
+
+func TestTreeMetrics(t *testing.T) {
+	tr := NewTree(transformTree())
+	root := tr.Root()
+	if tr.Size() != Size(root, false) || tr.Height() != Height(root, false) ||
+		tr.Breadth() != Breadth(root, false) || tr.Degree() != Degree(root, false) ||
+		tr.Width(2) != Width(root, 2, false) || tr.Stats().Size != tr.Size() ||
+		tr.String() != root.String() {
+		t.Errorf("the metrics of Tree differ from those of its root")
+	}
+}
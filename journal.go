@@ -0,0 +1,190 @@
+package otree
+
+// Journal records the changes made through a Tree, so they can be undone and
+// redone. Each change is a separate step, unless changes are grouped by
+// Group. Undoing and redoing is done through the Tree, so its other listeners
+// are notified as well. The journal assumes that the tree isn't changed
+// other than through the Tree.
+type Journal struct {
+	tree        *Tree
+	remove      func()
+	undo, redo  [][]*Change
+	group       []*Change
+	grouping    int
+	replaying   bool
+	checkpoints map[string]int
+}
+
+// NewJournal returns a Journal that records the changes made through t.
+func NewJournal(t *Tree) *Journal {
+	j := &Journal{tree: t, checkpoints: make(map[string]int)}
+	j.remove = t.AddListener(ListenerFuncs{After: j.record})
+	return j
+}
+
+// Close stops recording the changes of the tree.
+func (j *Journal) Close() {
+	j.remove()
+}
+
+// CanUndo tells if there is a step that can be undone.
+func (j *Journal) CanUndo() bool {
+	return len(j.undo) > 0
+}
+
+// CanRedo tells if there is a step that can be redone.
+func (j *Journal) CanRedo() bool {
+	return len(j.redo) > 0
+}
+
+// Group calls f and records all changes made during that call as a single
+// step. Calls to Group may be nested. It returns the error returned by f.
+// Changes made before f returned an error are recorded as well.
+func (j *Journal) Group(f func() error) error {
+	j.grouping++
+	err := f()
+	if j.grouping--; j.grouping == 0 && len(j.group) > 0 {
+		j.push(j.group)
+		j.group = nil
+	}
+	return err
+}
+
+// Checkpoint marks the current state under name, so it can be restored by
+// UndoTo. An existing checkpoint with the same name is replaced.
+func (j *Journal) Checkpoint(name string) {
+	j.checkpoints[name] = len(j.undo)
+}
+
+// Undo undoes the last step. If there is no such step ErrNothingToUndo will
+// be returned. If undoing one of the changes of the step fails, for instance
+// because a listener vetoes it, the changes of the step that were already
+// undone are redone, the step is kept and the error is returned.
+func (j *Journal) Undo() error {
+	l := len(j.undo)
+	if l == 0 {
+		return ErrNothingToUndo
+	}
+	step := j.undo[l-1]
+
+	for i := len(step) - 1; i >= 0; i-- {
+		if err := j.replay(step[i], true); err != nil {
+			for _, c := range step[i+1:] {
+				j.replay(c, false)
+			}
+			return err
+		}
+	}
+	j.undo = j.undo[:l-1]
+	j.redo = append(j.redo, step)
+	return nil
+}
+
+// Redo redoes the last undone step. If there is no such step
+// ErrNothingToRedo will be returned. If redoing one of the changes of the
+// step fails, the changes that were already redone are undone again, the
+// step is kept and the error is returned.
+func (j *Journal) Redo() error {
+	l := len(j.redo)
+	if l == 0 {
+		return ErrNothingToRedo
+	}
+	step := j.redo[l-1]
+
+	for i, c := range step {
+		if err := j.replay(c, false); err != nil {
+			for k := i - 1; k >= 0; k-- {
+				j.replay(step[k], true)
+			}
+			return err
+		}
+	}
+	j.redo = j.redo[:l-1]
+	j.undo = append(j.undo, step)
+	return nil
+}
+
+// UndoTo undoes all steps made after the checkpoint name. If the checkpoint
+// doesn't exist, or if its steps are undone and replaced by other changes,
+// ErrUnknownCheckpoint will be returned.
+func (j *Journal) UndoTo(name string) error {
+	mark, ok := j.checkpoints[name]
+	if !ok || mark > len(j.undo) {
+		return ErrUnknownCheckpoint
+	}
+	for len(j.undo) > mark {
+		if err := j.Undo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record is called after each change of the tree.
+func (j *Journal) record(c *Change) {
+	if j.replaying {
+		return
+	}
+	cp := *c
+	cp.Nodes = append([]*Node{}, c.Nodes...)
+	if j.grouping > 0 {
+		j.group = append(j.group, &cp)
+		return
+	}
+	j.push([]*Change{&cp})
+}
+
+// push adds a step and forgets the steps that can no longer be redone.
+func (j *Journal) push(step []*Change) {
+	if len(j.redo) > 0 {
+		j.redo = nil
+		for name, mark := range j.checkpoints {
+			if mark > len(j.undo) {
+				delete(j.checkpoints, name)
+			}
+		}
+	}
+	j.undo = append(j.undo, step)
+}
+
+// replay applies c, or its inverse if undo is true, to the tree.
+func (j *Journal) replay(c *Change, undo bool) error {
+	j.replaying = true
+	defer func() { j.replaying = false }()
+
+	t := j.tree
+	switch {
+	case c.Kind == Linked && !undo, c.Kind == Unlinked && undo:
+		return t.Link(c.Parent, c.Index, c.Nodes...)
+
+	case c.Kind == Linked && undo, c.Kind == Unlinked && !undo:
+		for range c.Nodes {
+			if _, err := t.RemoveSibling(c.Parent, c.Index); err != nil {
+				return err
+			}
+		}
+
+	case c.Kind == Replaced && !undo:
+		_, err := t.ReplaceSibling(c.Parent, c.Index, c.Nodes...)
+		return err
+
+	case c.Kind == Replaced && undo:
+		if len(c.Nodes) == 0 {
+			return t.Link(c.Parent, c.Index, c.Old)
+		}
+		for i := 1; i < len(c.Nodes); i++ {
+			if _, err := t.RemoveSibling(c.Parent, c.Index+1); err != nil {
+				return err
+			}
+		}
+		_, err := t.ReplaceSibling(c.Parent, c.Index, c.Old)
+		return err
+
+	case c.Kind == DataChanged && !undo:
+		return t.SetData(c.Nodes[0], c.NewData)
+
+	case c.Kind == DataChanged && undo:
+		return t.SetData(c.Nodes[0], c.OldData)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package otree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJournalUndoRedo(t *testing.T) {
+	root := New("root")
+	tr := NewTree(root)
+	j := NewJournal(tr)
+
+	if err := j.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Undo() returns error %v, should be %q", err, ErrNothingToUndo)
+	}
+	if err := j.Redo(); err != ErrNothingToRedo {
+		t.Errorf("Redo() returns error %v, should be %q", err, ErrNothingToRedo)
+	}
+
+	s0, s1, s2 := New("s0"), New("s1"), New("s2")
+	sa, sb := New("sa"), New("sb")
+	steps := []struct {
+		op   func() error
+		want string
+	}{
+		{func() error { return tr.Link(root, AtEnd, s0, s1, s2) }, "root[s0 s1 s2]"},
+		{func() error { return tr.Link(s1, AtEnd, New("c")) }, "root[s0 s1[c] s2]"},
+		{func() error { return tr.Remove(s0) }, "root[s1[c] s2]"},
+		{func() error { return tr.Replace(s1, sa, sb) }, "root[sa sb s2]"},
+		{func() error { return tr.SetData(s2, "x") }, "root[sa sb x]"},
+		{func() error { _, err := tr.RemoveAllSiblings(root); return err }, "root"},
+	}
+
+	for i, s := range steps {
+		if err := s.op(); err != nil {
+			t.Fatalf("%d: returns error %q, should be nil", i, err.Error())
+		}
+	}
+	for i := len(steps) - 1; i > 0; i-- {
+		if err := j.Undo(); err != nil {
+			t.Fatalf("Undo() returns error %q, should be nil", err.Error())
+		}
+		if got := root.String(); got != steps[i-1].want {
+			t.Errorf("Undo() results in %q, should be %q", got, steps[i-1].want)
+		}
+	}
+	j.Undo()
+	if got := root.String(); got != "root" || j.CanUndo() {
+		t.Errorf("undoing all steps results in %q", got)
+	}
+
+	for i := range steps {
+		if err := j.Redo(); err != nil {
+			t.Fatalf("Redo() returns error %q, should be nil", err.Error())
+		}
+		if got := root.String(); got != steps[i].want {
+			t.Errorf("Redo() results in %q, should be %q", got, steps[i].want)
+		}
+	}
+	if j.CanRedo() {
+		t.Errorf("CanRedo() returns true, should be false")
+	}
+}
+
+func TestJournalGroupsAndCheckpoints(t *testing.T) {
+	root := New("root")
+	tr := NewTree(root)
+	j := NewJournal(tr)
+
+	tr.Link(root, AtEnd, New("s0"))
+	j.Checkpoint("start")
+	err := j.Group(func() error {
+		tr.Link(root, AtEnd, New("s1"))
+		return j.Group(func() error {
+			tr.Link(root, AtEnd, New("s2"))
+			return tr.SetData(root, "top")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Group() returns error %q, should be nil", err.Error())
+	}
+	j.Checkpoint("grouped")
+	tr.Link(root, AtStart, New("s3"))
+
+	if err := j.UndoTo("grouped"); err != nil || root.String() != "top[s0 s1 s2]" {
+		t.Errorf("UndoTo(\"grouped\") returns %v and results in %q", err, root.String())
+	}
+	if err := j.Undo(); err != nil || root.String() != "root[s0]" {
+		t.Errorf("Undo() of a group returns %v and results in %q", err, root.String())
+	}
+	if err := j.Redo(); err != nil || root.String() != "top[s0 s1 s2]" {
+		t.Errorf("Redo() of a group returns %v and results in %q", err, root.String())
+	}
+	if err := j.UndoTo("start"); err != nil || root.String() != "root[s0]" {
+		t.Errorf("UndoTo(\"start\") returns %v and results in %q", err, root.String())
+	}
+
+	// a new change makes the checkpoint "grouped" unreachable
+	tr.Link(root, AtEnd, New("s4"))
+	if err := j.UndoTo("grouped"); err != ErrUnknownCheckpoint {
+		t.Errorf("UndoTo(\"grouped\") returns error %v, should be %q", err, ErrUnknownCheckpoint)
+	}
+	if err := j.UndoTo("unknown"); err != ErrUnknownCheckpoint {
+		t.Errorf("UndoTo(\"unknown\") returns error %v, should be %q", err, ErrUnknownCheckpoint)
+	}
+
+	j.Close()
+	tr.Link(root, AtEnd, New("s5"))
+	j.Undo()
+	if got := root.String(); got != "root[s0 s5]" {
+		t.Errorf("Undo() after Close() results in %q, should be %q", got, "root[s0 s5]")
+	}
+}
+
+func TestJournalVetoedReplay(t *testing.T) {
+	root := New("root")
+	tr := NewTree(root)
+	j := NewJournal(tr)
+	a, b := New("a"), New("b")
+	j.Group(func() error {
+		tr.Link(root, AtEnd, a)
+		return tr.Link(root, AtEnd, b)
+	})
+
+	errVeto := errors.New("veto")
+	var veto *Node
+	tr.AddListener(ListenerFuncs{Before: func(c *Change) error {
+		for _, n := range c.Nodes {
+			if n == veto {
+				return errVeto
+			}
+		}
+		return nil
+	}})
+
+	check := func(step, want string, canUndo, canRedo bool) {
+		t.Helper()
+		if got := root.String(); got != want {
+			t.Errorf("%s results in %q, should be %q", step, got, want)
+		}
+		if j.CanUndo() != canUndo || j.CanRedo() != canRedo {
+			t.Errorf("%s: CanUndo() and CanRedo() return %t and %t, should be %t and %t",
+				step, j.CanUndo(), j.CanRedo(), canUndo, canRedo)
+		}
+	}
+
+	veto = a
+	if err := j.Undo(); err != errVeto {
+		t.Errorf("Undo() returns error %v, should be %q", err, errVeto)
+	}
+	check("vetoed Undo()", "root[a b]", true, false)
+
+	veto = nil
+	if err := j.Undo(); err != nil {
+		t.Errorf("Undo() returns error %q, should be nil", err.Error())
+	}
+	check("Undo()", "root", false, true)
+
+	veto = b
+	if err := j.Redo(); err != errVeto {
+		t.Errorf("Redo() returns error %v, should be %q", err, errVeto)
+	}
+	check("vetoed Redo()", "root", false, true)
+
+	veto = nil
+	if err := j.Redo(); err != nil {
+		t.Errorf("Redo() returns error %q, should be nil", err.Error())
+	}
+	check("Redo()", "root[a b]", true, false)
+}
@@ -0,0 +1,273 @@
+package otree
+
+// siblingList holds the siblings of a node as a doubly linked list.
+type siblingList struct {
+	first, last *Node
+	n           int
+}
+
+// SetListStorage selects how nd stores its siblings. By default they are
+// stored in a slice, which gives fast access by index. When list is true they
+// are stored in a doubly linked list instead. Then linking nodes next to a
+// known sibling (InsertBefore, InsertAfter), removing a sibling (Remove) and
+// moving a node (MoveBefore, MoveAfter) don't need to copy or allocate,
+// while finding a sibling by index takes a walk along the list. The order of
+// the siblings and the results of all methods stay the same.
+func (nd *Node) SetListStorage(list bool) {
+	if list == (nd.list != nil) {
+		return
+	}
+
+	sblngs := nd.Siblings()
+	if list {
+		nd.list = &siblingList{}
+		nd.siblings = nil
+		nd.base = 0
+		nd.listInsert(nil, sblngs)
+		return
+	}
+
+	for _, n := range sblngs {
+		n.prev, n.next = nil, nil
+	}
+	nd.list = nil
+	if len(sblngs) > 0 {
+		nd.siblings = sblngs
+		nd.renumber(0)
+	}
+}
+
+// HasListStorage tells if nd stores its siblings in a linked list.
+func (nd *Node) HasListStorage() bool {
+	return nd.list != nil
+}
+
+// NextSibling returns the sibling that follows nd in its parent's list of
+// siblings. For the root node ErrParentMissing will be returned, for the
+// last sibling ErrNodeNotFound.
+func (nd *Node) NextSibling() (*Node, error) {
+	return nd.neighbour(1)
+}
+
+// PrevSibling returns the sibling that precedes nd in its parent's list of
+// siblings. For the root node ErrParentMissing will be returned, for the
+// first sibling ErrNodeNotFound.
+func (nd *Node) PrevSibling() (*Node, error) {
+	return nd.neighbour(-1)
+}
+
+// InsertBefore links nodes to nd's parent, just before nd. The nodes must be
+// roots of other trees. If one of them has a parent ErrNodeHasParent will be
+// returned, if one of them is the root of nd's tree or occurs more than once
+// ErrDuplicateNodeFound. In contrast to Link, the tree isn't searched for
+// the nodes.
+func (nd *Node) InsertBefore(nodes ...*Node) error {
+	return nd.insertNextTo(0, nodes)
+}
+
+// InsertAfter links nodes to nd's parent, just after nd. See InsertBefore.
+func (nd *Node) InsertAfter(nodes ...*Node) error {
+	return nd.insertNextTo(1, nodes)
+}
+
+// MoveBefore moves nd, with its subtree, to the position just before
+// sibling. If sibling is a root node ErrParentMissing will be returned, if it
+// is part of nd's subtree ErrInvalidMove.
+func (nd *Node) MoveBefore(sibling *Node) error {
+	return nd.moveNextTo(sibling, 0)
+}
+
+// MoveAfter moves nd, with its subtree, to the position just after sibling.
+// See MoveBefore.
+func (nd *Node) MoveAfter(sibling *Node) error {
+	return nd.moveNextTo(sibling, 1)
+}
+
+// neighbour returns the sibling at offset d from nd.
+func (nd *Node) neighbour(d int) (*Node, error) {
+	p := nd.parent
+	if p == nil {
+		return nil, ErrParentMissing
+	}
+
+	var n *Node
+	if p.list != nil {
+		if n = nd.next; d < 0 {
+			n = nd.prev
+		}
+	} else if i, err := nd.Index(); err == nil {
+		n, _ = p.Sibling(i + d)
+	}
+	if n == nil {
+		return nil, ErrNodeNotFound
+	}
+	return n, nil
+}
+
+// insertNextTo links nodes to nd's parent at offset from nd.
+func (nd *Node) insertNextTo(offset int, nodes []*Node) error {
+	p := nd.parent
+	if p == nil {
+		return ErrParentMissing
+	}
+	if err := nd.checkRoots(nodes); err != nil {
+		return err
+	}
+	p.linkNextTo(nd, offset, nodes)
+	return nil
+}
+
+// moveNextTo moves nd to the parent of sibling at offset from sibling.
+func (nd *Node) moveNextTo(sibling *Node, offset int) error {
+	p := sibling.parent
+	switch {
+	case p == nil:
+		return ErrParentMissing
+	case nd == sibling:
+		return nil
+	case isInSubtree(sibling, nd):
+		return ErrInvalidMove
+	}
+
+	if nd.parent != nil {
+		if err := nd.Remove(); err != nil {
+			return err
+		}
+	}
+	p.linkNextTo(sibling, offset, []*Node{nd})
+	return nil
+}
+
+// linkNextTo links nodes to nd at offset from its child sibling without any
+// checks.
+func (nd *Node) linkNextTo(sibling *Node, offset int, nodes []*Node) {
+	if nd.list == nil {
+		i, _ := sibling.Index()
+		nd.link(i+offset, nodes)
+		return
+	}
+
+	for _, n := range nodes {
+		n.parent = nd
+	}
+	if offset > 0 {
+		sibling = sibling.next
+	}
+	nd.listInsert(sibling, nodes)
+	nd.linked(nodes)
+}
+
+// checkRoots checks if nodes can be linked to nd's tree without searching it.
+func (nd *Node) checkRoots(nodes []*Node) error {
+	root := nd.Root()
+	var seen map[*Node]dummyType
+	if len(nodes) > 1 {
+		seen = make(map[*Node]dummyType, len(nodes))
+	}
+
+	for _, n := range nodes {
+		if n.parent != nil {
+			return ErrNodeHasParent
+		}
+		if n == root {
+			return ErrDuplicateNodeFound
+		}
+		if seen != nil {
+			if _, ok := seen[n]; ok {
+				return ErrDuplicateNodeFound
+			}
+			seen[n] = dummy
+		}
+	}
+	return nil
+}
+
+// listAt returns nd's sibling with index, or nil if index is out of range. It
+// walks along the list from the nearest end.
+func (nd *Node) listAt(index int) *Node {
+	l := nd.list
+	if index < 0 || index >= l.n {
+		return nil
+	}
+	if index < l.n/2 {
+		n := l.first
+		for ; index > 0; index-- {
+			n = n.next
+		}
+		return n
+	}
+	n := l.last
+	for i := l.n - 1; i > index; i-- {
+		n = n.prev
+	}
+	return n
+}
+
+// listIndex returns the index of child in nd's list of siblings.
+func (nd *Node) listIndex(child *Node) (int, error) {
+	if child.parent == nd {
+		i := 0
+		for n := child.prev; n != nil; n = n.prev {
+			i++
+		}
+		return i, nil
+	}
+
+	i := 0
+	for n := nd.list.first; n != nil; n = n.next {
+		if n == child {
+			return i, nil
+		}
+		i++
+	}
+	return -1, ErrNodeNotFound
+}
+
+// listInsert inserts nodes into nd's list of siblings before the child next.
+// If next is nil the nodes are appended.
+func (nd *Node) listInsert(next *Node, nodes []*Node) {
+	if len(nodes) == 0 {
+		return
+	}
+	l := nd.list
+
+	prev := l.last
+	if next != nil {
+		prev = next.prev
+	}
+	for _, n := range nodes {
+		n.prev = prev
+		if prev == nil {
+			l.first = n
+		} else {
+			prev.next = n
+		}
+		prev = n
+	}
+	prev.next = next
+	if next == nil {
+		l.last = prev
+	} else {
+		next.prev = prev
+	}
+	l.n += len(nodes)
+}
+
+// listUnlink removes child from nd's list of siblings without updating the
+// aggregates.
+func (nd *Node) listUnlink(child *Node) {
+	l := nd.list
+	if child.prev == nil {
+		l.first = child.next
+	} else {
+		child.prev.next = child.next
+	}
+	if child.next == nil {
+		l.last = child.prev
+	} else {
+		child.next.prev = child.prev
+	}
+	l.n--
+
+	child.prev, child.next, child.parent = nil, nil, nil
+}
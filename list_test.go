@@ -0,0 +1,150 @@
+package otree
+
+import (
+	"testing"
+)
+
+func TestListStorage(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := New("root")
+		root.SetListStorage(list)
+		if root.HasListStorage() != list {
+			t.Errorf("HasListStorage() returns %t, should be %t", !list, list)
+		}
+
+		s0, s1, s2 := New("s0"), New("s1"), New("s2")
+		steps := []struct {
+			op   func() error
+			want string
+		}{
+			{func() error { return root.Link(AtEnd, s0, s1) }, "root[s0 s1]"},
+			{func() error { return root.Link(-1, s2) }, "root[s2 s0 s1]"},
+			{func() error { return root.Link(1, New("a"), New("b")) }, "root[s2 a b s0 s1]"},
+			{func() error { _, err := root.RemoveSibling(1); return err }, "root[s2 b s0 s1]"},
+			{func() error { return s0.Remove() }, "root[s2 b s1]"},
+			{func() error { return s1.InsertBefore(New("c"), New("d")) }, "root[s2 b c d s1]"},
+			{func() error { return s2.InsertAfter(New("e")) }, "root[s2 e b c d s1]"},
+			{func() error { return s1.MoveBefore(s2) }, "root[s1 s2 e b c d]"},
+			{func() error { return s1.MoveAfter(s2) }, "root[s2 s1 e b c d]"},
+			{func() error { return s0.MoveAfter(s1) }, "root[s2 s1 s0 e b c d]"},
+			{func() error { return s2.Replace(New("f")) }, "root[f s1 s0 e b c d]"},
+			{func() error { _, err := root.ReplaceSibling(6, New("g")); return err }, "root[f s1 s0 e b c g]"},
+		}
+
+		for i, s := range steps {
+			if err := s.op(); err != nil {
+				t.Fatalf("list %t, %d: returns error %q, should be nil", list, i, err.Error())
+			}
+			if got := root.String(); got != s.want {
+				t.Errorf("list %t, %d: results in %q, should be %q", list, i, got, s.want)
+			}
+			for j, sbl := range root.Siblings() {
+				if idx, err := sbl.Index(); err != nil || idx != j {
+					t.Errorf("list %t, %d: Index() returns %d, %v, should be %d", list, i, idx, err, j)
+				}
+				if nd, err := root.Sibling(j); err != nil || nd != sbl {
+					t.Errorf("list %t, %d: Sibling(%d) returns %v, %v", list, i, j, nd, err)
+				}
+			}
+		}
+
+		if nd, err := s1.NextSibling(); err != nil || nd != s0 {
+			t.Errorf("list %t: NextSibling() returns %v, %v, should be s0", list, nd, err)
+		}
+		if nd, err := s1.PrevSibling(); err != nil || nd.Data != "f" {
+			t.Errorf("list %t: PrevSibling() returns %v, %v, should be f", list, nd, err)
+		}
+		first, _ := root.Sibling(0)
+		if _, err := first.PrevSibling(); err != ErrNodeNotFound {
+			t.Errorf("list %t: PrevSibling() returns error %v, should be %q", list, err, ErrNodeNotFound)
+		}
+		if _, err := root.NextSibling(); err != ErrParentMissing {
+			t.Errorf("list %t: NextSibling() returns error %v, should be %q", list, err, ErrParentMissing)
+		}
+
+		root.SetListStorage(!list)
+		if got, want := root.String(), "root[f s1 s0 e b c g]"; got != want {
+			t.Errorf("list %t: SetListStorage() results in %q, should be %q", list, got, want)
+		}
+		if nd, _ := s1.NextSibling(); nd != s0 {
+			t.Errorf("list %t: NextSibling() after SetListStorage() returns %v", list, nd)
+		}
+
+		nodes := root.RemoveAllSiblings()
+		if len(nodes) != 7 || !root.IsLeaf() || nodes[1] != s1 || s1.parent != nil {
+			t.Errorf("list %t: RemoveAllSiblings() returns %v", list, nodes)
+		}
+	}
+}
+
+func TestInsertAndMoveErrors(t *testing.T) {
+	root := New("root")
+	root.SetListStorage(true)
+	s0, s1 := New("s0"), New("s1")
+	root.Link(AtEnd, s0, s1)
+	c := New("c")
+	s0.Link(AtEnd, c)
+	free := New("free")
+
+	tests := []struct {
+		op  func() error
+		err error
+	}{
+		{func() error { return root.InsertAfter(New("x")) }, ErrParentMissing},
+		{func() error { return s0.InsertAfter(c) }, ErrNodeHasParent},
+		{func() error { return s0.InsertAfter(root) }, ErrDuplicateNodeFound},
+		{func() error { return s0.InsertAfter(free, free) }, ErrDuplicateNodeFound},
+		{func() error { return s0.MoveBefore(root) }, ErrParentMissing},
+		{func() error { return s0.MoveAfter(c) }, ErrInvalidMove},
+		{func() error { return root.MoveAfter(c) }, ErrInvalidMove},
+		{func() error { return s0.MoveAfter(s0) }, nil},
+	}
+	for i, tst := range tests {
+		if err := tst.op(); err != tst.err {
+			t.Errorf("%d: returns error %v, should be %v", i, err, tst.err)
+		}
+	}
+	if got := root.String(); got != "root[s0[c] s1]" {
+		t.Errorf("failing operations result in %q, should be %q", got, "root[s0[c] s1]")
+	}
+}
+
+func TestListStorageWithAggregates(t *testing.T) {
+	root := New(0)
+	root.SetListStorage(true)
+	root.Link(AtEnd, New(1), New(2))
+	EnableAggregates(root, sumAggregate())
+
+	first, _ := root.Sibling(0)
+	first.InsertAfter(New(3))
+	checkAggregates(t, "insert after", root)
+	first.Remove()
+	checkAggregates(t, "remove", root)
+}
+
+func TestListStorageManyChanges(t *testing.T) {
+	const n = 100000
+	root := New("root")
+	root.SetListStorage(true)
+	first := New(0)
+	root.Link(AtEnd, first)
+
+	nodes := make([]*Node, n)
+	for i := range nodes {
+		nodes[i] = New(i + 1)
+		if err := first.InsertAfter(nodes[i]); err != nil {
+			t.Fatalf("InsertAfter() returns error %q, should be nil", err.Error())
+		}
+	}
+	for i := 0; i < n; i += 2 {
+		nodes[i].MoveBefore(first)
+	}
+	for _, nd := range nodes {
+		if err := nd.Remove(); err != nil {
+			t.Fatalf("Remove() returns error %q, should be nil", err.Error())
+		}
+	}
+	if got := root.String(); got != "root[0]" {
+		t.Errorf("changes result in %q, should be %q", got, "root[0]")
+	}
+}
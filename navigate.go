@@ -0,0 +1,56 @@
+package otree
+
+// Children returns nd's child nodes. It is an alias for Siblings, whose name
+// refers to the children being siblings of each other.
+func (nd *Node) Children() []*Node {
+	return nd.Siblings()
+}
+
+// FirstChild returns nd's first child. If nd is a leaf ErrNodeNotFound will
+// be returned.
+func (nd *Node) FirstChild() (*Node, error) {
+	if nd.list != nil && nd.list.first != nil {
+		return nd.list.first, nil
+	}
+	return nd.Sibling(0)
+}
+
+// LastChild returns nd's last child. If nd is a leaf ErrNodeNotFound will be
+// returned.
+func (nd *Node) LastChild() (*Node, error) {
+	if nd.list != nil && nd.list.last != nil {
+		return nd.list.last, nil
+	}
+	return nd.Sibling(nd.Degree() - 1)
+}
+
+// PeerSiblings returns the other children of nd's parent, in their order. For
+// the root node ErrParentMissing will be returned.
+func (nd *Node) PeerSiblings() ([]*Node, error) {
+	p, err := nd.Parent()
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]*Node, 0, p.Degree()-1)
+	for _, sbl := range p.Siblings() {
+		if sbl != nd {
+			peers = append(peers, sbl)
+		}
+	}
+	return peers, nil
+}
+
+// IsFirst tells if nd is the first child of its parent. The root node is
+// both first and last.
+func (nd *Node) IsFirst() bool {
+	_, err := nd.PrevSibling()
+	return err != nil
+}
+
+// IsLast tells if nd is the last child of its parent. The root node is both
+// first and last.
+func (nd *Node) IsLast() bool {
+	_, err := nd.NextSibling()
+	return err != nil
+}
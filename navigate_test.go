@@ -0,0 +1,66 @@
+package otree
+
+import (
+	"testing"
+)
+
+func TestNavigate(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := New("root")
+		root.SetListStorage(list)
+		s0, s1, s2 := New("s0"), New("s1"), New("s2")
+		root.Link(AtEnd, s0, s1, s2)
+
+		if got := nodesString(root.Children()); got != "[s0@root s1@root s2@root]" {
+			t.Errorf("list %t: Children() returns %s", list, got)
+		}
+		if nd, err := root.FirstChild(); err != nil || nd != s0 {
+			t.Errorf("list %t: FirstChild() returns %v, %v, should be s0", list, nd, err)
+		}
+		if nd, err := root.LastChild(); err != nil || nd != s2 {
+			t.Errorf("list %t: LastChild() returns %v, %v, should be s2", list, nd, err)
+		}
+		if _, err := s0.FirstChild(); err != ErrNodeNotFound {
+			t.Errorf("list %t: FirstChild() returns error %v, should be %q", list, err, ErrNodeNotFound)
+		}
+		if _, err := s0.LastChild(); err != ErrNodeNotFound {
+			t.Errorf("list %t: LastChild() returns error %v, should be %q", list, err, ErrNodeNotFound)
+		}
+
+		if nd, err := s1.NextSibling(); err != nil || nd != s2 {
+			t.Errorf("list %t: NextSibling() returns %v, %v, should be s2", list, nd, err)
+		}
+		if _, err := s2.NextSibling(); err != ErrNodeNotFound {
+			t.Errorf("list %t: NextSibling() returns error %v, should be %q", list, err, ErrNodeNotFound)
+		}
+		if nd, err := s1.PrevSibling(); err != nil || nd != s0 {
+			t.Errorf("list %t: PrevSibling() returns %v, %v, should be s0", list, nd, err)
+		}
+
+		peers, err := s1.PeerSiblings()
+		if got := nodesString(peers); err != nil || got != "[s0@root s2@root]" {
+			t.Errorf("list %t: PeerSiblings() returns %s, %v", list, got, err)
+		}
+		if _, err := root.PeerSiblings(); err != ErrParentMissing {
+			t.Errorf("list %t: PeerSiblings() returns error %v, should be %q", list, err, ErrParentMissing)
+		}
+
+		tests := []struct {
+			nd          *Node
+			first, last bool
+		}{
+			{root, true, true},
+			{s0, true, false},
+			{s1, false, false},
+			{s2, false, true},
+		}
+		for _, tst := range tests {
+			if got := tst.nd.IsFirst(); got != tst.first {
+				t.Errorf("list %t: %v.IsFirst() returns %t, should be %t", list, tst.nd.Data, got, tst.first)
+			}
+			if got := tst.nd.IsLast(); got != tst.last {
+				t.Errorf("list %t: %v.IsLast() returns %t, should be %t", list, tst.nd.Data, got, tst.last)
+			}
+		}
+	}
+}
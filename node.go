@@ -14,9 +14,15 @@ import (
 // An internal node is any node of a tree that has one or more child nodes. An
 // external node, or leaf node, is any node that does not have child nodes.
 type Node struct {
-	Data     interface{} // stored data
-	parent   *Node       // parent node
-	siblings []*Node     // sibling nodes
+	Data     interface{}  // stored data
+	parent   *Node        // parent node
+	siblings []*Node      // sibling nodes
+	pos      int          // position in the parent's list of siblings
+	base     int          // position of the first sibling
+	list     *siblingList // siblings stored as a linked list, see SetListStorage
+	prev     *Node        // previous sibling when stored in a linked list
+	next     *Node        // next sibling when stored in a linked list
+	agg      *aggregates  // cached aggregates, nil when not enabled
 }
 
 // WalkFunc is a function type that can be performed on all nodes in a
@@ -38,6 +44,9 @@ func (nd *Node) Ancestors() (ancestors []*Node) {
 
 // Degree returns nd's degree, i.e. the number of siblings.
 func (nd *Node) Degree() (d int) {
+	if nd.list != nil {
+		return nd.list.n
+	}
 	if nd.siblings != nil {
 		d = len(nd.siblings)
 	}
@@ -52,24 +61,45 @@ func (nd *Node) Distance(node *Node) (int, error) {
 	return len(path) - 1, err
 }
 
+// Find returns the first node, in the order of Walk, of nd and its
+// descendants for which f returns true. If there is no such node
+// ErrNodeNotFound will be returned.
+func (nd *Node) Find(f func(node *Node) bool) (*Node, error) {
+	var found *Node
+	nd.walk(func(node *Node, level int) bool {
+		if f(node) {
+			found = node
+			return false
+		}
+		return true
+	})
+
+	if found == nil {
+		return nil, ErrNodeNotFound
+	}
+	return found, nil
+}
+
 // Height returns nd's height, i.e. the longest downward path to a leaf.
 func (nd *Node) Height() (height int) {
-	l := nd.Level()
-
-	f := func(node *Node, data interface{}) {
-		if node.IsLeaf() {
-			if h := node.Level() - l; h > height {
-				height = h
-			}
-		}
+	if nd.agg != nil {
+		return nd.agg.height
 	}
 
-	nd.Walk(f, nil)
+	nd.walk(func(node *Node, level int) bool {
+		if level > height {
+			height = level
+		}
+		return true
+	})
 	return
 }
 
 // Index returns the index in the list of siblings to which nd belongs.
 // Finding the index of a root node results in returning ErrParentMissing.
+// When the siblings are stored in a slice, the index is stored in nd, so it is
+// found without searching the list. When they are stored in a linked list, see
+// SetListStorage, the preceding siblings are counted, which takes O(index).
 func (nd *Node) Index() (int, error) {
 	p, err := nd.Parent()
 	if err != nil {
@@ -80,7 +110,7 @@ func (nd *Node) Index() (int, error) {
 
 // IsLeaf tells if nd is an external/leaf node.
 func (nd *Node) IsLeaf() bool {
-	return nd.siblings == nil || len(nd.siblings) == 0
+	return nd.Degree() == 0
 }
 
 // Level returns nd's level, i.e. the zero-based counting of edges along
@@ -99,7 +129,20 @@ func (nd *Node) Level() (n int) {
 // The constants AtStart and AtEnd can be used to link nodes at the start or
 // end of the list of siblings.
 func (nd *Node) Link(index int, nodes ...*Node) error {
+	if err := nd.checkLink(nodes); err != nil {
+		return err
+	}
+	nd.link(index, nodes)
+	return nil
+}
+
+// checkLink checks if nodes can be linked to nd. If any of the nodes, or any
+// of their descendants, is already part of nd's tree or occurs more than once
+// ErrDuplicateNodeFound will be returned. The nodes may hold siblings of nd
+// that are passed as leaving, as these are unlinked before nodes are linked.
+func (nd *Node) checkLink(nodes []*Node, leaving ...*Node) error {
 	newNodes := make(map[*Node]dummyType)
+	var relinked map[*Node]dummyType // nodes of leaving that are part of nodes
 	found := false
 
 	// f is a WalkFunc to test if there are any duplicate nodes in a tree
@@ -109,6 +152,9 @@ func (nd *Node) Link(index int, nodes ...*Node) error {
 			if !found && collectNodes.(bool) {
 				newNodes[node] = dummy
 			}
+			if found && relinked != nil {
+				found = !nd.isRelinked(node, relinked)
+			}
 		}
 	}
 
@@ -120,20 +166,70 @@ func (nd *Node) Link(index int, nodes ...*Node) error {
 	}
 
 	// tests for duplicates in the node's tree
+	if len(leaving) > 0 {
+		relinked = make(map[*Node]dummyType)
+		for _, n := range leaving {
+			if _, ok := newNodes[n]; ok {
+				relinked[n] = dummy
+			}
+		}
+	}
 	if nd.Root().Walk(f, false); found {
 		return ErrDuplicateNodeFound
 	}
+	return nil
+}
+
+// isRelinked tells if node is part of the subtree of one of nd's siblings in
+// relinked.
+func (nd *Node) isRelinked(node *Node, relinked map[*Node]dummyType) bool {
+	for n := node; n.parent != nil; n = n.parent {
+		if n.parent == nd {
+			_, ok := relinked[n]
+			return ok
+		}
+	}
+	return false
+}
 
+// link links nodes to nd before the child with index without any checks.
+func (nd *Node) link(index int, nodes []*Node) {
 	for _, n := range nodes {
 		n.parent = nd
 	}
+	if nd.list != nil {
+		if index < 0 {
+			index = 0
+		}
+		nd.listInsert(nd.listAt(index), nodes)
+		nd.linked(nodes)
+		return
+	}
 
-	if nd.siblings == nil {
-		nd.siblings = make([]*Node, 0)
+	l := len(nd.siblings)
+	if index >= l {
+		// appending keeps the positions of the current siblings
+		nd.siblings = append(nd.siblings, nodes...)
+		nd.renumber(l)
+	} else {
+		nd.siblings = insertNodes(nd.siblings, nodes, index)
+		nd.base = 0
+		nd.renumber(0)
 	}
-	nd.siblings = insertNodes(nd.siblings, nodes, index)
+	nd.linked(nodes)
+}
 
-	return nil
+// renumber stores the positions of nd's siblings, starting at index.
+func (nd *Node) renumber(index int) {
+	for i := index; i < len(nd.siblings); i++ {
+		nd.siblings[i].pos = nd.base + i
+	}
+}
+
+// adopt appends nodes to nd's siblings without checking for duplicates. It
+// must only be used for nodes that are known not to be part of any tree.
+func (nd *Node) adopt(nodes ...*Node) {
+	nd.link(AtEnd, nodes)
 }
 
 // New returns a new node with some data stored into it.
@@ -166,6 +262,11 @@ func (nd *Node) Remove() error {
 	if p == nil {
 		return ErrCannotRemoveRootNode
 	}
+	if p.list != nil {
+		p.listUnlink(nd)
+		p.unlinked(nd)
+		return nil
+	}
 	i, err := nd.Index()
 	if err != nil {
 		return err
@@ -181,16 +282,47 @@ func (nd *Node) RemoveAllSiblings() []*Node {
 	if nd.IsLeaf() {
 		return []*Node{}
 	}
-	sblngs := nd.siblings
+	sblngs := nd.unlinkSiblings()
+	nd.unlinked(sblngs...)
+
+	return sblngs
+}
+
+// unlinkSiblings removes all nd's siblings without updating the aggregates.
+// It returns the removed siblings.
+func (nd *Node) unlinkSiblings() []*Node {
+	sblngs := nd.Siblings()
 	nd.siblings = nil
+	nd.base = 0
+	if nd.list != nil {
+		for _, n := range sblngs {
+			n.prev, n.next = nil, nil
+		}
+		nd.list = &siblingList{}
+	}
 
 	for _, n := range sblngs {
 		n.parent = nil
 	}
-
 	return sblngs
 }
 
+// attach stores nodes as the siblings of nd, which must be a leaf, without
+// any checks and without updating the aggregates.
+func (nd *Node) attach(nodes []*Node) {
+	for _, n := range nodes {
+		n.parent = nd
+	}
+	if nd.list != nil {
+		nd.listInsert(nil, nodes)
+		return
+	}
+	if len(nodes) > 0 {
+		nd.siblings = append([]*Node{}, nodes...)
+		nd.renumber(0)
+	}
+}
+
 // RemoveSibling removes the nd's child with the provided index in the list
 // of siblings. It returns the removed sibling. Its parent is invalidated.
 // If there is no node with the given index, ErrNodeNotFound will be returned.
@@ -199,19 +331,45 @@ func (nd *Node) RemoveSibling(index int) (*Node, error) {
 	if nd.IsLeaf() || index < 0 || index >= l {
 		return nil, ErrNodeNotFound
 	}
+	if nd.list != nil {
+		node := nd.listAt(index)
+		nd.listUnlink(node)
+		nd.unlinked(node)
+		return node, nil
+	}
 
-	siblings := make([]*Node, l-1)
-	copy(siblings, nd.siblings[:index])
 	node := nd.siblings[index]
-	copy(siblings[index:], nd.siblings[index+1:])
-
-	if len(siblings) == 0 {
+	switch {
+	case l == 1:
 		nd.siblings = nil
-	} else {
+		nd.base = 0
+	case (index == 0 || index == l-1) && (l-1)&(l-2) != 0:
+		// the first or last sibling is removed without copying; the capacity
+		// is limited, so appending doesn't overwrite the vacated slot
+		if index == 0 {
+			nd.siblings = nd.siblings[1:l:l]
+			nd.base++
+		} else {
+			nd.siblings = nd.siblings[:index:index]
+		}
+	default:
+		// a new slice is allocated, so slices returned earlier by Siblings()
+		// don't change. For the first or last sibling this is only done when
+		// the number of siblings reaches a power of two, so the removed nodes
+		// aren't kept alive by the old slice for long.
+		siblings := make([]*Node, l-1)
+		copy(siblings, nd.siblings[:index])
+		copy(siblings[index:], nd.siblings[index+1:])
 		nd.siblings = siblings
+		if index == 0 {
+			nd.base++
+		} else {
+			nd.renumber(index)
+		}
 	}
 
 	node.parent = nil
+	nd.unlinked(node)
 	return node, nil
 }
 
@@ -254,23 +412,46 @@ func (nd *Node) Root() *Node {
 // Sibling returns nd's child in the list of siblings with the provided
 // index.
 func (nd *Node) Sibling(index int) (*Node, error) {
-	if nd.IsLeaf() || index < 0 || index >= len(nd.siblings) {
+	if nd.IsLeaf() || index < 0 || index >= nd.Degree() {
 		return nil, ErrNodeNotFound
 	}
+	if nd.list != nil {
+		return nd.listAt(index), nil
+	}
 	return nd.siblings[index], nil
 }
 
-// Siblings returns all the siblings
+// Siblings returns all the siblings. When they are stored as a linked list a
+// new slice is allocated. Otherwise the slice shares its storage with nd, so
+// it must not be changed. Later changes of nd's siblings don't change it.
+// Its capacity is limited to its length, so appending to it never changes nd.
 func (nd *Node) Siblings() []*Node {
 	if nd.IsLeaf() {
 		return []*Node{}
 	}
-	return nd.siblings
+	if nd.list != nil {
+		sblngs := make([]*Node, 0, nd.list.n)
+		for n := nd.list.first; n != nil; n = n.next {
+			sblngs = append(sblngs, n)
+		}
+		return sblngs
+	}
+	l := len(nd.siblings)
+	return nd.siblings[:l:l]
 }
 
 // SiblingIndex returns the index of child in nd's list of siblings. If it
 // cannot be found it returns ErrNodeNotFound.
 func (nd *Node) SiblingIndex(child *Node) (int, error) {
+	if nd.list != nil {
+		return nd.listIndex(child)
+	}
+	if child.parent == nd {
+		if i := child.pos - nd.base; i >= 0 && i < len(nd.siblings) && nd.siblings[i] == child {
+			return i, nil
+		}
+	}
+
 	if nd.siblings != nil {
 		for i, sbl := range nd.siblings {
 			if sbl == child {
@@ -282,44 +463,205 @@ func (nd *Node) SiblingIndex(child *Node) (int, error) {
 	return -1, ErrNodeNotFound
 }
 
-// String creates a string that displays nd's content and recursivly the
-// contents of all of its newNodes.
+// Splice removes deleteCount of nd's siblings, starting at the child with
+// index start, and links nodes in their place. It returns the removed
+// siblings, their parents are invalidated. If start is negative it will be
+// set to zero, if it is larger than the index of nd's last sibling the nodes
+// will be appended. So AtStart and AtEnd can be used. deleteCount is limited
+// to the number of siblings from start. The nodes are checked like Link does
+// before nd is changed, so an error leaves nd unchanged. The nodes may hold
+// removed siblings, so they are linked again, like the elements of an array
+// in JavaScript's Array.splice. These are returned too, with nd as their
+// parent.
+func (nd *Node) Splice(start, deleteCount int, nodes ...*Node) ([]*Node, error) {
+	d := nd.Degree()
+	if start < 0 {
+		start = 0
+	} else if start > d {
+		start = d
+	}
+	if deleteCount < 0 {
+		deleteCount = 0
+	} else if deleteCount > d-start {
+		deleteCount = d - start
+	}
+
+	removed := make([]*Node, deleteCount)
+	var next *Node // sibling following the removed ones in list storage
+	if nd.list != nil {
+		next = nd.listAt(start)
+		for i := range removed {
+			removed[i] = next
+			next = next.next
+		}
+	} else {
+		copy(removed, nd.siblings[start:])
+	}
+	if err := nd.checkLink(nodes, removed...); err != nil {
+		return nil, err
+	}
+
+	if nd.list != nil {
+		for _, n := range removed {
+			nd.listUnlink(n)
+		}
+		for _, n := range nodes {
+			n.parent = nd
+		}
+		nd.listInsert(next, nodes)
+	} else {
+		for _, n := range removed {
+			n.parent = nil
+		}
+		for _, n := range nodes {
+			n.parent = nd
+		}
+
+		if l := d - deleteCount + len(nodes); l == 0 {
+			nd.siblings = nil
+			nd.base = 0
+		} else {
+			siblings := make([]*Node, l)
+			copy(siblings, nd.siblings[:start])
+			copy(siblings[start:], nodes)
+			copy(siblings[start+len(nodes):], nd.siblings[start+deleteCount:])
+			nd.siblings = siblings
+			nd.renumber(start)
+		}
+	}
+
+	nd.siblingsChanged(nodes, removed)
+	return removed, nil
+}
+
+// String creates a string that displays nd's content and the contents of all
+// of its descendants.
 func (nd *Node) String() string {
 	sb := strings.Builder{}
 
-	fmt.Fprintf(&sb, "%v", nd.Data)
-	if nd.siblings != nil && len(nd.siblings) > 0 {
-		fmt.Fprintf(&sb, "[")
-		sep := ""
-		for _, sbl := range nd.siblings {
-			fmt.Fprintf(&sb, "%s%s", sep, sbl.String())
+	sep := ""
+	Events(nd, func(e Event) error {
+		switch e.Kind {
+		case EventBegin:
+			fmt.Fprintf(&sb, "%s%v[", sep, e.Data)
+			sep = ""
+		case EventLeaf:
+			fmt.Fprintf(&sb, "%s%v", sep, e.Data)
+			sep = " "
+		case EventEnd:
+			fmt.Fprintf(&sb, "]")
 			sep = " "
 		}
-		fmt.Fprintf(&sb, "]")
-
-	}
+		return nil
+	})
 	return sb.String()
 }
 
 // Walk executes f for nd and all of its descendants. data will be used
 // as the second argument for f.
 func (nd *Node) Walk(f WalkFunc, data interface{}) {
-	f(nd, data)
-	if nd.siblings != nil {
-		for _, sbl := range nd.siblings {
-			sbl.Walk(f, data)
-		}
-	}
+	nd.walk(func(node *Node, level int) bool {
+		f(node, data)
+		return true
+	})
 }
 
 // WalkUp executes f for nd and all of its descendants. data will be used
-// as the second argument for f. In contrast to Walk, f will be performed to
-// nd's siblings before executing it on nd itself.
+// as the second argument for f. In contrast to Walk, f will be performed on
+// the descendants of a node before executing it on the node itself
+// (post-order).
 func (nd *Node) WalkUp(f WalkFunc, data interface{}) {
-	if nd.siblings != nil {
-		for _, sbl := range nd.siblings {
-			sbl.Walk(f, data)
+	nd.traverse(true, false, func(node *Node, level int) bool {
+		f(node, data)
+		return true
+	})
+}
+
+// WalkReverse is like Walk, but it visits the siblings of each node from the
+// last to the first one.
+func (nd *Node) WalkReverse(f WalkFunc, data interface{}) {
+	nd.traverse(false, true, func(node *Node, level int) bool {
+		f(node, data)
+		return true
+	})
+}
+
+// WalkUpReverse is like WalkUp, but it visits the siblings of each node from
+// the last to the first one.
+func (nd *Node) WalkUpReverse(f WalkFunc, data interface{}) {
+	nd.traverse(true, true, func(node *Node, level int) bool {
+		f(node, data)
+		return true
+	})
+}
+
+// walk calls f for nd and all of its descendants in the order of Walk. level
+// is the level of node relative to nd. If f returns false the walk stops.
+func (nd *Node) walk(f func(node *Node, level int) bool) {
+	nd.traverse(false, false, f)
+}
+
+// walkItem is an item on the stack used by traverse.
+type walkItem struct {
+	nd    *Node
+	level int  // level relative to the start of the traversal
+	done  bool // true when the node's siblings are already on the stack
+}
+
+// traverse calls f for nd and all of its descendants. If post is true f is
+// called for the descendants of a node before the node itself, otherwise
+// after it. If reverse is true the siblings of a node are visited from the
+// last to the first one. If f returns false the traversal stops.
+// It uses a stack instead of recursion, so the height of the tree is not
+// limited by the size of the goroutine's stack.
+func (nd *Node) traverse(post, reverse bool, f func(node *Node, level int) bool) {
+	stack := []walkItem{{nd: nd}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if post && !it.done {
+			// visit the node again after its siblings
+			it.done = true
+			stack = append(stack, it)
+			stack = it.nd.pushSiblings(stack, it.level+1, !reverse)
+			continue
+		}
+		if !f(it.nd, it.level) {
+			return
+		}
+		if !post {
+			stack = it.nd.pushSiblings(stack, it.level+1, !reverse)
+		}
+	}
+}
+
+// pushSiblings appends nd's siblings with level to stack. If reverse is true
+// they are appended from the last to the first one, so the first one is
+// popped first.
+func (nd *Node) pushSiblings(stack []walkItem, level int, reverse bool) []walkItem {
+	if l := nd.list; l != nil {
+		if reverse {
+			for n := l.last; n != nil; n = n.prev {
+				stack = append(stack, walkItem{nd: n, level: level})
+			}
+		} else {
+			for n := l.first; n != nil; n = n.next {
+				stack = append(stack, walkItem{nd: n, level: level})
+			}
+		}
+		return stack
+	}
+
+	sblngs := nd.siblings
+	if reverse {
+		for i := len(sblngs) - 1; i >= 0; i-- {
+			stack = append(stack, walkItem{nd: sblngs[i], level: level})
+		}
+	} else {
+		for _, sbl := range sblngs {
+			stack = append(stack, walkItem{nd: sbl, level: level})
 		}
 	}
-	f(nd, data)
+	return stack
 }
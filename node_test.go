@@ -3,6 +3,7 @@ package otree
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -551,3 +552,304 @@ func TestRemove(t *testing.T) {
 	}
 
 }
+
+func TestFind(t *testing.T) {
+	root := New(0)
+	children := []*Node{New(1), New(2)}
+	root.Link(AtEnd, children...)
+	children[0].Link(AtEnd, New(3), New(4))
+	children[1].Link(AtEnd, New(4))
+
+	tests := []struct {
+		data int
+		want *Node
+		err  error
+	}{
+		{0, root, nil},
+		{2, children[1], nil},
+		{4, children[0].siblings[1], nil},
+		{5, nil, ErrNodeNotFound},
+	}
+
+	for _, tst := range tests {
+		got, err := root.Find(func(nd *Node) bool { return nd.Data == tst.data })
+		if err != tst.err {
+			t.Errorf("Find(%d) returns error %v, should be %v", tst.data, err, tst.err)
+		} else if got != tst.want {
+			t.Errorf("Find(%d) returns %v, should be %v", tst.data, got, tst.want)
+		}
+	}
+}
+
+func TestIndexAfterChanges(t *testing.T) {
+	root := New("root")
+	held := []*Node{New(0), New(1), New(2), New(3), New(4), New(5)}
+	root.Link(AtEnd, held...)
+	sblngs := root.Siblings()
+
+	check := func(step string) {
+		t.Helper()
+		for i, sbl := range root.Siblings() {
+			if idx, err := sbl.Index(); err != nil || idx != i {
+				t.Errorf("%s: %v.Index() returns %d, %v, should be %d, nil",
+					step, sbl.Data, idx, err, i)
+			}
+		}
+	}
+
+	root.RemoveSibling(0)
+	check("remove first")
+	root.RemoveSibling(root.Degree() - 1)
+	check("remove last")
+	root.Link(AtEnd, New(6), New(7))
+	check("append")
+	root.RemoveSibling(2)
+	check("remove middle")
+	root.Link(1, New(8))
+	check("insert")
+	root.Siblings()[0].Remove()
+	check("remove")
+
+	if got := root.String(); got != "root[8 2 4 6 7]" {
+		t.Errorf("changes result in %q, should be %q", got, "root[8 2 4 6 7]")
+	}
+	for i, nd := range sblngs {
+		if nd != held[i] {
+			t.Errorf("changes modify a slice returned earlier by Siblings()")
+		}
+	}
+}
+
+func TestRemoveManySiblings(t *testing.T) {
+	const n = 50000
+	for _, fromEnd := range []bool{false, true} {
+		root := New("root")
+		nodes := make([]*Node, n)
+		for i := range nodes {
+			nodes[i] = New(i)
+		}
+		root.Link(AtEnd, nodes...)
+
+		for i := range nodes {
+			nd := nodes[i]
+			if fromEnd {
+				nd = nodes[n-i-1]
+			}
+			if err := nd.Remove(); err != nil {
+				t.Fatalf("Remove() returns error %q, should be nil", err.Error())
+			}
+		}
+		if !root.IsLeaf() {
+			t.Errorf("removing all siblings leaves %d siblings", root.Degree())
+		}
+	}
+}
+
+// deepChain returns a tree of n nodes in which each node has one sibling,
+// except the last one.
+func deepChain(n int) (root, last *Node) {
+	var b Builder
+	for i := 0; i < n-1; i++ {
+		b.Begin(i)
+	}
+	b.Leaf(n - 1)
+	for i := 0; i < n-1; i++ {
+		b.End()
+	}
+	root, _ = b.Result()
+	last, _ = root.Find(func(nd *Node) bool { return nd.IsLeaf() })
+	return root, last
+}
+
+func TestDeepTree(t *testing.T) {
+	const n = 1000000
+	root, last := deepChain(n)
+	if last == nil || last.Data != n-1 {
+		t.Fatalf("Find() returns %v, should be %d", last, n-1)
+	}
+
+	if got := root.Height(); got != n-1 {
+		t.Errorf("Height() returns %d, should be %d", got, n-1)
+	}
+
+	count := 0
+	root.Walk(func(nd *Node, data interface{}) { count++ }, nil)
+	if count != n {
+		t.Errorf("Walk() visits %d nodes, should be %d", count, n)
+	}
+	count = 0
+	root.WalkUp(func(nd *Node, data interface{}) { count++ }, nil)
+	if count != n {
+		t.Errorf("WalkUp() visits %d nodes, should be %d", count, n)
+	}
+
+	s := root.String()
+	if want := "999998[999999]" + strings.Repeat("]", n-2); !strings.HasPrefix(s, "0[1[2[") ||
+		!strings.HasSuffix(s, want) {
+		t.Errorf("String() returns %.20q...%q", s, s[len(s)-20:])
+	}
+
+	if err := last.Link(AtEnd, New("x")); err != nil {
+		t.Errorf("Link() returns error %q, should be nil", err.Error())
+	}
+	if err := last.Link(AtEnd, root); err != ErrDuplicateNodeFound {
+		t.Errorf("Link() returns error %v, should be %q", err, ErrDuplicateNodeFound)
+	}
+
+	root.SetListStorage(true)
+	if got := root.Height(); got != n {
+		t.Errorf("Height() in list mode returns %d, should be %d", got, n)
+	}
+}
+
+func TestWalkOrders(t *testing.T) {
+	tests := []struct {
+		name string
+		walk func(nd *Node, f WalkFunc, data interface{})
+		want string
+	}{
+		{"Walk", (*Node).Walk, "[0 1 3 4 6 7 8 2 5]"},
+		{"WalkUp", (*Node).WalkUp, "[3 7 8 6 4 1 5 2 0]"},
+		{"WalkReverse", (*Node).WalkReverse, "[0 2 5 1 4 6 8 7 3]"},
+		{"WalkUpReverse", (*Node).WalkUpReverse, "[5 2 8 7 6 4 3 1 0]"},
+	}
+
+	for _, list := range []bool{false, true} {
+		root := transformTree()
+		nd, _ := root.Find(func(nd *Node) bool { return nd.Data == 6 })
+		nd.Link(AtEnd, New(7), New(8))
+		if list {
+			root.Walk(func(nd *Node, data interface{}) { nd.SetListStorage(true) }, nil)
+		}
+
+		for _, tst := range tests {
+			var got []interface{}
+			tst.walk(root, func(nd *Node, data interface{}) {
+				got = append(got, nd.Data)
+			}, nil)
+			if s := fmt.Sprint(got); s != tst.want {
+				t.Errorf("%s() with list %t visits %s, should be %s", tst.name, list, s, tst.want)
+			}
+		}
+	}
+}
+
+func TestSplice(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := New(0)
+		root.SetListStorage(list)
+		root.Link(AtEnd, New(1), New(2), New(3), New(4), New(5))
+		EnableAggregates(root, sumAggregate())
+
+		tests := []struct {
+			start, deleteCount int
+			nodes              []*Node
+			removed, want      string
+		}{
+			{1, 2, []*Node{New(6), New(7), New(8)}, "[2 3]", "0[1 6 7 8 4 5]"},
+			{AtStart, 1, nil, "[1]", "0[6 7 8 4 5]"},
+			{-3, 0, []*Node{New(9)}, "[]", "0[9 6 7 8 4 5]"},
+			{AtEnd, 4, []*Node{New(10)}, "[]", "0[9 6 7 8 4 5 10]"},
+			{5, AtEnd, nil, "[5 10]", "0[9 6 7 8 4]"},
+			{2, -1, []*Node{New(11)}, "[]", "0[9 6 11 7 8 4]"},
+			{0, AtEnd, nil, "[9 6 11 7 8 4]", "0"},
+			{0, 0, []*Node{New(12), New(13)}, "[]", "0[12 13]"},
+		}
+		for i, tst := range tests {
+			removed, err := root.Splice(tst.start, tst.deleteCount, tst.nodes...)
+			if err != nil {
+				t.Errorf("list %t, %d: Splice() returns error %q, should be nil", list, i, err.Error())
+			}
+			var data []interface{}
+			for _, n := range removed {
+				data = append(data, n.Data)
+				if n.parent != nil {
+					t.Errorf("list %t, %d: Splice() returns a node with a parent", list, i)
+				}
+			}
+			if got := fmt.Sprint(data); got != tst.removed {
+				t.Errorf("list %t, %d: Splice() removes %s, should be %s", list, i, got, tst.removed)
+			}
+			if got := root.String(); got != tst.want {
+				t.Errorf("list %t, %d: Splice() results in %q, should be %q", list, i, got, tst.want)
+			}
+			for j, sbl := range root.Siblings() {
+				if idx, err := sbl.Index(); err != nil || idx != j {
+					t.Errorf("list %t, %d: Index() returns %d, %v, should be %d", list, i, idx, err, j)
+				}
+			}
+			checkAggregates(t, fmt.Sprintf("list %t, %d: Splice()", list, i), root)
+		}
+
+		// removed siblings can be linked again
+		sblngs := root.Siblings()
+		sblngs[1].Link(AtEnd, New(14))
+		if _, err := root.Splice(0, 2, sblngs[1], sblngs[0]); err != nil {
+			t.Errorf("list %t: swapping Splice() returns error %q, should be nil", list, err.Error())
+		}
+		if got := root.String(); got != "0[13[14] 12]" {
+			t.Errorf("list %t: swapping Splice() results in %q, should be %q", list, got, "0[13[14] 12]")
+		}
+		checkAggregates(t, fmt.Sprintf("list %t: swapping Splice()", list), root)
+
+		sblngs = root.Siblings()
+		failing := [][]*Node{
+			{sblngs[1]},               // not removed
+			{sblngs[0].Siblings()[0]}, // part of a removed subtree that isn't linked again
+			{sblngs[0], sblngs[0]},    // twice
+			{sblngs[0].Siblings()[0], sblngs[0]},
+		}
+		for i, nodes := range failing {
+			if _, err := root.Splice(0, 1, nodes...); err != ErrDuplicateNodeFound {
+				t.Errorf("list %t, %d: Splice() returns error %v, should be %q", list, i, err, ErrDuplicateNodeFound)
+			}
+			if got := root.String(); got != "0[13[14] 12]" {
+				t.Errorf("list %t, %d: failing Splice() results in %q, should be %q", list, i, got, "0[13[14] 12]")
+			}
+		}
+	}
+}
+
+func TestSiblingsSharing(t *testing.T) {
+	root := New("root")
+	root.Link(AtEnd, New(0), New(1), New(2))
+	root.Link(AtEnd, New(3)) // leaves spare capacity in the siblings slice
+	root.RemoveSibling(3)
+	root.Link(AtEnd, New(3))
+
+	s := root.Siblings()
+	x := New("x")
+	mine := append(s, x)
+	root.Link(AtEnd, New("y"))
+	if mine[len(s)] != x {
+		t.Errorf("Link() changes a slice appended to the result of Siblings()")
+	}
+
+	// remove siblings while ranging over a slice returned by Siblings()
+	for _, sbl := range root.Siblings() {
+		if d, ok := sbl.Data.(int); ok && d%2 == 1 {
+			sbl.Remove()
+		}
+	}
+	if got := root.String(); got != "root[0 2 y]" {
+		t.Errorf("removing while ranging over Siblings() results in %q, should be %q", got, "root[0 2 y]")
+	}
+
+	held := root.Siblings()
+	want := fmt.Sprint(held)
+	for _, index := range []int{0, 1, 0} {
+		root.RemoveSibling(index)
+		root.Link(AtEnd, New(index))
+		if got := fmt.Sprint(held); got != want {
+			t.Errorf("RemoveSibling(%d) changes a slice returned earlier by Siblings()", index)
+		}
+	}
+	if got := root.String(); got != "root[0 1 0]" {
+		t.Errorf("RemoveSibling() results in %q, should be %q", got, "root[0 1 0]")
+	}
+	for i, sbl := range root.Siblings() {
+		if idx, err := sbl.Index(); err != nil || idx != i {
+			t.Errorf("Index() returns %d, %v, should be %d", idx, err, i)
+		}
+	}
+}
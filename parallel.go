@@ -0,0 +1,163 @@
+package otree
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ParallelFunc is a function type that can be performed on all nodes in a
+// (sub)tree by ParallelWalk.
+type ParallelFunc func(nd *Node) error
+
+// ParallelOptions holds the options for ParallelWalk. The zero value gives
+// the defaults.
+type ParallelOptions struct {
+	Workers         int  // maximum number of concurrent calls, GOMAXPROCS when not positive
+	ParentsFirst    bool // a node is done before f is called for its siblings
+	ContinueOnError bool // keep on walking after f returned an error
+}
+
+// WalkErrors holds the errors returned by the calls of a ParallelFunc.
+type WalkErrors []error
+
+// Error implements the error interface.
+func (we WalkErrors) Error() string {
+	if len(we) == 1 {
+		return we[0].Error()
+	}
+	return fmt.Sprintf("otree: %d errors, first one: %v", len(we), we[0])
+}
+
+// Unwrap returns the errors.
+func (we WalkErrors) Unwrap() []error {
+	return we
+}
+
+// parallelWalk holds the state of a ParallelWalk.
+type parallelWalk struct {
+	f       ParallelFunc
+	opts    ParallelOptions
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*Node    // nodes waiting for a worker
+	pending int        // nodes queued or being visited
+	stopped bool       // no more nodes will be visited
+	errs    WalkErrors // errors returned by f
+}
+
+// ParallelWalk executes f for root and all of its descendants, using at most
+// opts.Workers goroutines. f is called exactly once for each node, so
+// concurrent calls never get the same node. f may change the data of its node,
+// but not the structure of the tree, and it must synchronise access to other
+// nodes by itself. The order of the calls isn't defined, except that when
+// opts.ParentsFirst is true the call for a node returns before the calls for
+// its siblings start. Then the descendants of a node for which f returned an
+// error are not visited.
+// When f returns an error no more nodes are visited, unless
+// opts.ContinueOnError is true. The calls that are running are completed and
+// the errors are returned as WalkErrors. If ctx is done before all nodes are
+// visited, and f returned no errors, ctx.Err() will be returned.
+// Other goroutines must not change the tree during the walk.
+func ParallelWalk(ctx context.Context, root *Node, f ParallelFunc, opts ParallelOptions) error {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	pw := &parallelWalk{f: f, opts: opts, queue: []*Node{root}, pending: 1}
+	pw.cond = sync.NewCond(&pw.mu)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pw.stop()
+		case <-done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			pw.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case len(pw.errs) > 0:
+		return pw.errs
+	case pw.pending > 0:
+		return ctx.Err()
+	}
+	return nil
+}
+
+// work visits nodes from the queue until all nodes are visited or the walk is
+// stopped.
+func (pw *parallelWalk) work(ctx context.Context) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	for {
+		for len(pw.queue) == 0 && pw.pending > 0 && !pw.stopped {
+			pw.cond.Wait()
+		}
+		if pw.pending == 0 || pw.stopped {
+			return
+		}
+		if ctx.Err() != nil {
+			pw.stopped = true
+			pw.cond.Broadcast()
+			return
+		}
+
+		l := len(pw.queue)
+		nd := pw.queue[l-1]
+		pw.queue = pw.queue[:l-1]
+		if !pw.opts.ParentsFirst {
+			pw.enqueue(nd)
+		}
+
+		pw.mu.Unlock()
+		err := pw.f(nd)
+		pw.mu.Lock()
+
+		if err != nil {
+			pw.errs = append(pw.errs, err)
+			if !pw.opts.ContinueOnError {
+				pw.stopped = true
+				pw.cond.Broadcast()
+			}
+		} else if pw.opts.ParentsFirst {
+			pw.enqueue(nd)
+		}
+		if pw.pending--; pw.pending == 0 {
+			pw.cond.Broadcast()
+		}
+	}
+}
+
+// enqueue adds nd's siblings to the queue. pw.mu must be locked.
+func (pw *parallelWalk) enqueue(nd *Node) {
+	d := nd.Degree()
+	if d == 0 {
+		return
+	}
+	pw.queue = append(pw.queue, nd.Siblings()...)
+	pw.pending += d
+	for i := 0; i < d; i++ {
+		pw.cond.Signal()
+	}
+}
+
+// stop stops the walk.
+func (pw *parallelWalk) stop() {
+	pw.mu.Lock()
+	pw.stopped = true
+	pw.cond.Broadcast()
+	pw.mu.Unlock()
+}
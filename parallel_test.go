@@ -0,0 +1,119 @@
+package otree
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// wideTree returns a tree with a root, n siblings and n siblings for each of
+// them.
+func wideTree(n int) *Node {
+	root := New(0)
+	for i := 0; i < n; i++ {
+		sbl := New(0)
+		for j := 0; j < n; j++ {
+			sbl.adopt(New(0))
+		}
+		root.adopt(sbl)
+	}
+	return root
+}
+
+func TestParallelWalk(t *testing.T) {
+	const n = 30
+	for _, parentsFirst := range []bool{false, true} {
+		root := wideTree(n)
+		var mu sync.Mutex
+		visited := make(map[*Node]int)
+		f := func(nd *Node) error {
+			nd.Data = nd.Data.(int) + 1
+			mu.Lock()
+			defer mu.Unlock()
+			if p := nd.parent; parentsFirst && p != nil && visited[p] == 0 {
+				t.Errorf("ParallelWalk() visits a sibling before its parent is done")
+			}
+			visited[nd]++
+			return nil
+		}
+
+		err := ParallelWalk(context.Background(), root, f, ParallelOptions{Workers: 4, ParentsFirst: parentsFirst})
+		if err != nil {
+			t.Errorf("ParallelWalk() returns error %q, should be nil", err.Error())
+		}
+		if len(visited) != 1+n+n*n {
+			t.Errorf("ParallelWalk() visits %d nodes, should be %d", len(visited), 1+n+n*n)
+		}
+		root.Walk(func(nd *Node, data interface{}) {
+			if visited[nd] != 1 || nd.Data != 1 {
+				t.Errorf("ParallelWalk() visits a node %d times", visited[nd])
+			}
+		}, nil)
+	}
+}
+
+func TestParallelWalkErrors(t *testing.T) {
+	const n = 20
+	root := wideTree(n)
+	fail := errors.New("fail")
+
+	var calls int32
+	f := func(nd *Node) error {
+		atomic.AddInt32(&calls, 1)
+		if nd.IsLeaf() {
+			return fail
+		}
+		return nil
+	}
+	err := ParallelWalk(context.Background(), root, f, ParallelOptions{ContinueOnError: true})
+	if we, ok := err.(WalkErrors); !ok || len(we) != n*n || we[0] != fail {
+		t.Errorf("ParallelWalk() returns error %v, should hold %d errors", err, n*n)
+	}
+	if calls != 1+n+n*n {
+		t.Errorf("ParallelWalk() calls f %d times, should be %d", calls, 1+n+n*n)
+	}
+
+	calls = 0
+	err = ParallelWalk(context.Background(), root, f, ParallelOptions{Workers: 1})
+	if we, ok := err.(WalkErrors); !ok || len(we) != 1 || err.Error() != fail.Error() {
+		t.Errorf("ParallelWalk() returns error %v, should be %q", err, fail)
+	}
+	if calls >= 1+n+n*n {
+		t.Errorf("ParallelWalk() doesn't stop after an error")
+	}
+
+	// the siblings of a failing node are skipped
+	calls = 0
+	g := func(nd *Node) error {
+		atomic.AddInt32(&calls, 1)
+		if nd.parent == root {
+			return fail
+		}
+		return nil
+	}
+	err = ParallelWalk(context.Background(), root, g, ParallelOptions{ParentsFirst: true, ContinueOnError: true})
+	if we, ok := err.(WalkErrors); !ok || len(we) != n || calls != 1+n {
+		t.Errorf("ParallelWalk() returns %d errors after %d calls, should be %d after %d", len(we), calls, n, 1+n)
+	}
+}
+
+func TestParallelWalkCancel(t *testing.T) {
+	root := wideTree(20)
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	f := func(nd *Node) error {
+		if atomic.AddInt32(&calls, 1) == 10 {
+			cancel()
+		}
+		return nil
+	}
+
+	if err := ParallelWalk(ctx, root, f, ParallelOptions{Workers: 2}); err != context.Canceled {
+		t.Errorf("ParallelWalk() returns error %v, should be %q", err, context.Canceled)
+	}
+	if err := ParallelWalk(ctx, root, f, ParallelOptions{}); err != context.Canceled {
+		t.Errorf("ParallelWalk() returns error %v, should be %q", err, context.Canceled)
+	}
+}
@@ -0,0 +1,340 @@
+package otree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Persistent is an immutable node. Operations that change a tree of
+// persistent nodes return a new root and leave the original tree untouched.
+// Unchanged subtrees are shared between both versions, only the nodes on the
+// path from the root to the change are copied. As persistent nodes don't
+// know their parents, nodes are addressed by a path: the indexes of the
+// siblings to follow, starting at the root. An empty path addresses the root.
+type Persistent struct {
+	data     interface{}
+	siblings []*Persistent
+}
+
+// NewPersistent returns a new persistent node with data and siblings.
+func NewPersistent(data interface{}, siblings ...*Persistent) *Persistent {
+	p := &Persistent{data: data}
+	if len(siblings) > 0 {
+		p.siblings = append([]*Persistent{}, siblings...)
+	}
+	return p
+}
+
+// Freeze returns a persistent copy of the (sub)tree starting at nd.
+func Freeze(nd *Node) *Persistent {
+	p := &Persistent{data: nd.Data}
+	if sblngs := nd.Siblings(); len(sblngs) > 0 {
+		p.siblings = make([]*Persistent, len(sblngs))
+		for i, sbl := range sblngs {
+			p.siblings[i] = Freeze(sbl)
+		}
+	}
+	return p
+}
+
+// Thaw returns a mutable copy of the (sub)tree starting at p.
+func (p *Persistent) Thaw() *Node {
+	nd := New(p.data)
+	if len(p.siblings) > 0 {
+		nodes := make([]*Node, len(p.siblings))
+		for i, sbl := range p.siblings {
+			nodes[i] = sbl.Thaw()
+		}
+		nd.adopt(nodes...)
+	}
+	return nd
+}
+
+// Data returns the data stored in p.
+func (p *Persistent) Data() interface{} {
+	return p.data
+}
+
+// Degree returns p's degree, i.e. the number of siblings.
+func (p *Persistent) Degree() int {
+	return len(p.siblings)
+}
+
+// Sibling returns p's child in the list of siblings with the provided index.
+func (p *Persistent) Sibling(index int) (*Persistent, error) {
+	if index < 0 || index >= len(p.siblings) {
+		return nil, ErrNodeNotFound
+	}
+	return p.siblings[index], nil
+}
+
+// Siblings returns a copy of the list of p's siblings.
+func (p *Persistent) Siblings() []*Persistent {
+	return append([]*Persistent{}, p.siblings...)
+}
+
+// At returns the node at path. If there is no such node ErrNodeNotFound will
+// be returned.
+func (p *Persistent) At(path ...int) (*Persistent, error) {
+	node := p
+	for _, i := range path {
+		var err error
+		if node, err = node.Sibling(i); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// WithData returns a new root for a tree in which the node at path holds
+// data.
+func (p *Persistent) WithData(path []int, data interface{}) (*Persistent, error) {
+	return p.update(path, func(node *Persistent) (*Persistent, error) {
+		return &Persistent{data: data, siblings: node.siblings}, nil
+	})
+}
+
+// WithChild returns a new root for a tree in which child is inserted in the
+// list of siblings of the node at path, just before the child with index.
+// The constants AtStart and AtEnd can be used as index.
+func (p *Persistent) WithChild(path []int, index int, child *Persistent) (*Persistent, error) {
+	return p.update(path, func(node *Persistent) (*Persistent, error) {
+		l := len(node.siblings)
+		if index > l {
+			index = l
+		} else if index < 0 {
+			index = 0
+		}
+		sblngs := make([]*Persistent, l+1)
+		copy(sblngs, node.siblings[:index])
+		sblngs[index] = child
+		copy(sblngs[index+1:], node.siblings[index:])
+		return &Persistent{data: node.data, siblings: sblngs}, nil
+	})
+}
+
+// WithoutChild returns a new root for a tree in which the child with index is
+// removed from the list of siblings of the node at path.
+func (p *Persistent) WithoutChild(path []int, index int) (*Persistent, error) {
+	return p.update(path, func(node *Persistent) (*Persistent, error) {
+		l := len(node.siblings)
+		if index < 0 || index >= l {
+			return nil, ErrNodeNotFound
+		}
+		if l == 1 {
+			return &Persistent{data: node.data}, nil
+		}
+		sblngs := make([]*Persistent, l-1)
+		copy(sblngs, node.siblings[:index])
+		copy(sblngs[index:], node.siblings[index+1:])
+		return &Persistent{data: node.data, siblings: sblngs}, nil
+	})
+}
+
+// Move returns a new root for a tree in which the node at from is moved to
+// the list of siblings of the node at to, just before the child with index.
+// to and index are interpreted in the original tree. Moving the root results
+// in ErrCannotRemoveRootNode, moving a node into its own subtree in
+// ErrInvalidMove.
+func (p *Persistent) Move(from, to []int, index int) (*Persistent, error) {
+	l := len(from)
+	if l == 0 {
+		return nil, ErrCannotRemoveRootNode
+	}
+	node, err := p.At(from...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.At(to...); err != nil {
+		return nil, err
+	}
+	if len(to) >= l && samePath(to[:l], from) {
+		return nil, ErrInvalidMove
+	}
+
+	// correct to and index for the removal of the node
+	parent, i := from[:l-1], from[l-1]
+	to = append([]int{}, to...)
+	switch {
+	case len(to) >= l && samePath(to[:l-1], parent) && to[l-1] > i:
+		to[l-1]--
+	case samePath(to, parent) && index > i:
+		index--
+	}
+
+	r, err := p.WithoutChild(parent, i)
+	if err != nil {
+		return nil, err
+	}
+	return r.WithChild(to, index, node)
+}
+
+// String creates a string that displays p's content and recursively the
+// contents of all of its siblings, like Node.String().
+func (p *Persistent) String() string {
+	sb := strings.Builder{}
+
+	fmt.Fprintf(&sb, "%v", p.data)
+	if len(p.siblings) > 0 {
+		fmt.Fprintf(&sb, "[")
+		sep := ""
+		for _, sbl := range p.siblings {
+			fmt.Fprintf(&sb, "%s%s", sep, sbl.String())
+			sep = " "
+		}
+		fmt.Fprintf(&sb, "]")
+	}
+	return sb.String()
+}
+
+// update returns a new root for a tree in which the node at path is replaced
+// by the result of f. Only the nodes along path are copied.
+func (p *Persistent) update(path []int, f func(*Persistent) (*Persistent, error)) (*Persistent, error) {
+	if len(path) == 0 {
+		return f(p)
+	}
+	sbl, err := p.Sibling(path[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := sbl.update(path[1:], f)
+	if err != nil {
+		return nil, err
+	}
+	return p.withSibling(path[0], n), nil
+}
+
+// withSibling returns a copy of p with the child at index replaced by sbl.
+func (p *Persistent) withSibling(index int, sbl *Persistent) *Persistent {
+	if p.siblings[index] == sbl {
+		return p
+	}
+	sblngs := append([]*Persistent{}, p.siblings...)
+	sblngs[index] = sbl
+	return &Persistent{data: p.data, siblings: sblngs}
+}
+
+// samePath tells if the paths a and b are equal.
+func samePath(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Zipper is a cursor in a tree of persistent nodes. It keeps track of the
+// path to its focus, so it can move in every direction and apply local
+// changes. A Zipper is a value: moving or changing it returns a new Zipper
+// and leaves the original one untouched.
+type Zipper struct {
+	focus  *Persistent
+	crumbs *crumb
+}
+
+// crumb holds what is needed to rebuild a parent when moving up.
+type crumb struct {
+	parent *Persistent
+	index  int
+	up     *crumb
+}
+
+// Zipper returns a Zipper with its focus on p.
+func (p *Persistent) Zipper() Zipper {
+	return Zipper{focus: p}
+}
+
+// Focus returns the node at z's focus.
+func (z Zipper) Focus() *Persistent {
+	return z.focus
+}
+
+// Index returns the index of the focus in its parent's list of siblings, or
+// -1 when the focus is the root.
+func (z Zipper) Index() int {
+	if z.crumbs == nil {
+		return -1
+	}
+	return z.crumbs.index
+}
+
+// Path returns the path from the root to the focus.
+func (z Zipper) Path() []int {
+	var path []int
+	for c := z.crumbs; c != nil; c = c.up {
+		path = append(path, c.index)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Down moves the focus to the child with index. ok is false if there is no
+// such child.
+func (z Zipper) Down(index int) (zz Zipper, ok bool) {
+	sbl, err := z.focus.Sibling(index)
+	if err != nil {
+		return z, false
+	}
+	return Zipper{focus: sbl, crumbs: &crumb{z.focus, index, z.crumbs}}, true
+}
+
+// Up moves the focus to its parent, applying all changes made below it. ok
+// is false if the focus is the root.
+func (z Zipper) Up() (zz Zipper, ok bool) {
+	c := z.crumbs
+	if c == nil {
+		return z, false
+	}
+	return Zipper{focus: c.parent.withSibling(c.index, z.focus), crumbs: c.up}, true
+}
+
+// Next moves the focus to its next sibling. ok is false if there is none.
+func (z Zipper) Next() (zz Zipper, ok bool) {
+	return z.sideways(1)
+}
+
+// Prev moves the focus to its previous sibling. ok is false if there is none.
+func (z Zipper) Prev() (zz Zipper, ok bool) {
+	return z.sideways(-1)
+}
+
+// sideways moves the focus d positions in the list of siblings.
+func (z Zipper) sideways(d int) (Zipper, bool) {
+	c := z.crumbs
+	if c == nil {
+		return z, false
+	}
+	parent := c.parent.withSibling(c.index, z.focus)
+	sbl, err := parent.Sibling(c.index + d)
+	if err != nil {
+		return z, false
+	}
+	return Zipper{focus: sbl, crumbs: &crumb{parent, c.index + d, c.up}}, true
+}
+
+// Root returns the root of the tree, with all changes applied.
+func (z Zipper) Root() *Persistent {
+	for {
+		up, ok := z.Up()
+		if !ok {
+			return z.focus
+		}
+		z = up
+	}
+}
+
+// Replace replaces the focus by p.
+func (z Zipper) Replace(p *Persistent) Zipper {
+	return Zipper{focus: p, crumbs: z.crumbs}
+}
+
+// WithData replaces the data of the focus.
+func (z Zipper) WithData(data interface{}) Zipper {
+	return z.Replace(&Persistent{data: data, siblings: z.focus.siblings})
+}
@@ -0,0 +1,131 @@
+package otree
+
+import (
+	"errors"
+	"testing"
+)
+
+// persistentTree returns the persistent tree 0[1[3 4] 2[5]]
+func persistentTree() *Persistent {
+	return NewPersistent(0,
+		NewPersistent(1, NewPersistent(3), NewPersistent(4)),
+		NewPersistent(2, NewPersistent(5)))
+}
+
+func TestFreezeAndThaw(t *testing.T) {
+	root := transformTree()
+	p := Freeze(root)
+	if got, want := p.String(), root.String(); got != want {
+		t.Errorf("Freeze() returns %q, should be %q", got, want)
+	}
+
+	nd := p.Thaw()
+	if got, want := nd.String(), root.String(); got != want {
+		t.Errorf("Thaw() returns %q, should be %q", got, want)
+	}
+	if nd == root || nd.siblings[0].parent != nd {
+		t.Errorf("Thaw() doesn't return a new tree")
+	}
+}
+
+func TestPersistentOperations(t *testing.T) {
+	p := persistentTree()
+	want := p.String()
+
+	tests := []struct {
+		op   func() (*Persistent, error)
+		want string
+		err  error
+	}{
+		{func() (*Persistent, error) { return p.WithData([]int{0, 1}, 40) }, "0[1[3 40] 2[5]]", nil},
+		{func() (*Persistent, error) { return p.WithData(nil, 10) }, "10[1[3 4] 2[5]]", nil},
+		{func() (*Persistent, error) { return p.WithData([]int{2}, 10) }, "", ErrNodeNotFound},
+		{func() (*Persistent, error) { return p.WithChild([]int{1}, AtStart, NewPersistent(6)) }, "0[1[3 4] 2[6 5]]", nil},
+		{func() (*Persistent, error) { return p.WithChild([]int{1, 0}, AtEnd, NewPersistent(6)) }, "0[1[3 4] 2[5[6]]]", nil},
+		{func() (*Persistent, error) { return p.WithoutChild([]int{0}, 0) }, "0[1[4] 2[5]]", nil},
+		{func() (*Persistent, error) { return p.WithoutChild([]int{1}, 0) }, "0[1[3 4] 2]", nil},
+		{func() (*Persistent, error) { return p.WithoutChild([]int{1}, 1) }, "", ErrNodeNotFound},
+		{func() (*Persistent, error) { return p.Move([]int{0, 0}, []int{1}, AtEnd) }, "0[1[4] 2[5 3]]", nil},
+		{func() (*Persistent, error) { return p.Move([]int{0}, []int{1, 0}, AtEnd) }, "0[2[5[1[3 4]]]]", nil},
+		{func() (*Persistent, error) { return p.Move([]int{0, 0}, []int{0}, AtEnd) }, "0[1[4 3] 2[5]]", nil},
+		{func() (*Persistent, error) { return p.Move([]int{0}, nil, 2) }, "0[2[5] 1[3 4]]", nil},
+		{func() (*Persistent, error) { return p.Move([]int{0}, []int{0, 1}, 0) }, "", ErrInvalidMove},
+		{func() (*Persistent, error) { return p.Move(nil, []int{0}, 0) }, "", ErrCannotRemoveRootNode},
+		{func() (*Persistent, error) { return p.Move([]int{0}, []int{3}, 0) }, "", ErrNodeNotFound},
+	}
+
+	for i, tst := range tests {
+		got, err := tst.op()
+		switch {
+		case !errors.Is(err, tst.err):
+			t.Errorf("%d: returns error %v, should be %v", i, err, tst.err)
+		case err == nil && got.String() != tst.want:
+			t.Errorf("%d: returns %q, should be %q", i, got.String(), tst.want)
+		}
+		if s := p.String(); s != want {
+			t.Fatalf("%d: modifies the original tree into %q, should be %q", i, s, want)
+		}
+	}
+}
+
+func TestPersistentSharing(t *testing.T) {
+	p := persistentTree()
+	q, _ := p.WithData([]int{0, 1}, 40)
+
+	if q == p {
+		t.Errorf("WithData() returns the original root")
+	}
+	if q.siblings[0] == p.siblings[0] {
+		t.Errorf("WithData() doesn't copy the changed path")
+	}
+	if q.siblings[1] != p.siblings[1] || q.siblings[0].siblings[0] != p.siblings[0].siblings[0] {
+		t.Errorf("WithData() doesn't share unchanged subtrees")
+	}
+}
+
+func TestZipper(t *testing.T) {
+	p := persistentTree()
+	z := p.Zipper()
+
+	if _, ok := z.Up(); ok {
+		t.Errorf("Up() at the root returns true, should be false")
+	}
+	if _, ok := z.Down(2); ok {
+		t.Errorf("Down(2) returns true, should be false")
+	}
+
+	z, _ = z.Down(0)
+	z, _ = z.Down(1)
+	if got := z.Focus().Data(); got != 4 {
+		t.Errorf("Focus() is %v, should be 4", got)
+	}
+	if got := z.Path(); !samePath(got, []int{0, 1}) {
+		t.Errorf("Path() returns %v, should be [0 1]", got)
+	}
+	if _, ok := z.Next(); ok {
+		t.Errorf("Next() at the last sibling returns true, should be false")
+	}
+
+	z = z.WithData(40)
+	z, _ = z.Prev()
+	if z.Index() != 0 {
+		t.Errorf("Index() returns %d, should be 0", z.Index())
+	}
+	z = z.Replace(NewPersistent(30, NewPersistent(7)))
+	z, _ = z.Up()
+	z, ok := z.Next()
+	if !ok {
+		t.Fatalf("Next() returns false, should be true")
+	}
+
+	root := z.Root()
+	if got, want := root.String(), "0[1[30[7] 40] 2[5]]"; got != want {
+		t.Errorf("Root() returns %q, should be %q", got, want)
+	}
+	if root.siblings[1] != p.siblings[1] {
+		t.Errorf("Root() doesn't share unchanged subtrees")
+	}
+	if got, want := p.String(), "0[1[3 4] 2[5]]"; got != want {
+		t.Errorf("the original tree is changed into %q, should be %q", got, want)
+	}
+}
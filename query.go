@@ -0,0 +1,469 @@
+package otree
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LabelFunc is a function type that returns the label of a node. Labels are
+// used by a Query to match the name tests in its steps.
+type LabelFunc func(nd *Node) string
+
+// AttrFunc is a function type that returns the value of the attribute name of
+// a node. ok must be false if nd doesn't have such an attribute. Attributes
+// are used by a Query to evaluate predicates like [@data="x"].
+type AttrFunc func(nd *Node, name string) (value string, ok bool)
+
+// Query is a compiled path expression that selects nodes from a tree. It is
+// created by Compile and can be used repeatedly.
+//
+// A path expression is a list of steps separated by "/" or "//". A leading
+// "/" makes the path absolute, i.e. it starts at the root of the tree. A "//"
+// selects the current nodes and all of their descendants before applying the
+// next step. Each step has an optional axis, a name test and zero or more
+// predicates:
+//
+//	axis::name[predicate]...
+//
+// The axis is one of child (the default), descendant, descendant-or-self,
+// self, parent, ancestor, following-sibling or preceding-sibling. The steps
+// "." and ".." are short for self::* and parent::*, so they can be followed
+// by predicates too. The name test "*" matches any node, other names (bare or
+// quoted) are compared with the node's label.
+// A predicate is either a zero-based index, matching nodes for which Index()
+// returns that number, or an attribute test like [@data], [@data="x"] or
+// [@data!="x"].
+//
+// Example:
+//
+//	/root/*/item[2]//leaf[@data="x"]
+type Query struct {
+	Label LabelFunc // labels nodes for name tests, nil means fmt.Sprint(nd.Data)
+	Attr  AttrFunc  // gives attributes for predicates, nil only knows @data
+
+	expr     string
+	absolute bool
+	steps    []step
+}
+
+// SyntaxError describes an error in a path expression. Pos is the zero-based
+// byte offset in Expr where the error was detected.
+type SyntaxError struct {
+	Expr string // the expression
+	Pos  int    // offset of the error
+	Msg  string // description of the error
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("otree: syntax error at position %d in %q: %s",
+		e.Pos, e.Expr, e.Msg)
+}
+
+type axis int
+
+const (
+	axisChild axis = iota
+	axisDescendant
+	axisDescendantOrSelf
+	axisSelf
+	axisParent
+	axisAncestor
+	axisFollowingSibling
+	axisPrecedingSibling
+)
+
+var axisNames = map[string]axis{
+	"child":              axisChild,
+	"descendant":         axisDescendant,
+	"descendant-or-self": axisDescendantOrSelf,
+	"self":               axisSelf,
+	"parent":             axisParent,
+	"ancestor":           axisAncestor,
+	"following-sibling":  axisFollowingSibling,
+	"preceding-sibling":  axisPrecedingSibling,
+}
+
+// predicate is a test on a node. If attr is empty index must match the
+// node's index.
+type predicate struct {
+	index int
+	attr  string
+	op    string // "", "=" or "!="
+	value string
+}
+
+// step is a single step in a path expression.
+type step struct {
+	deep  bool // preceded by "//"
+	axis  axis
+	name  string // "*" matches any node
+	preds []predicate
+}
+
+// Compile parses expr and returns a Query that can be used to select nodes.
+// If expr isn't a valid path expression a *SyntaxError will be returned.
+func Compile(expr string) (*Query, error) {
+	p := parser{expr: expr}
+	q := &Query{expr: expr}
+
+	if q.absolute = strings.HasPrefix(expr, "/"); expr == "/" {
+		return q, nil
+	}
+
+	for {
+		deep := false
+		switch {
+		case p.peek("//"):
+			deep = true
+		case p.peek("/"):
+		case len(q.steps) > 0:
+			return nil, p.errorf("'/' expected")
+		}
+		if p.atEnd() {
+			return nil, p.errorf("step expected")
+		}
+
+		s, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		s.deep = deep
+		q.steps = append(q.steps, s)
+
+		if p.atEnd() {
+			return q, nil
+		}
+	}
+}
+
+// String returns the expression from which q was compiled.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Select returns all nodes selected by q, using nd as the context node. For
+// absolute expressions only the root of nd's tree is relevant, "/" selects
+// the root itself. Every node appears at most once in the result, and the
+// nodes are in the order of Walk (document order).
+func (q *Query) Select(nd *Node) []*Node {
+	root := nd.Root()
+
+	// a nil entry represents the (virtual) parent of the root node
+	current := []*Node{nd}
+	if q.absolute {
+		current = []*Node{nil}
+	}
+
+	for _, s := range q.steps {
+		if s.deep {
+			current = q.apply(current, root, step{axis: axisDescendantOrSelf, name: "*"})
+		}
+		current = q.apply(current, root, s)
+	}
+
+	if len(q.steps) == 0 && q.absolute {
+		return []*Node{root}
+	}
+
+	result := make([]*Node, 0, len(current))
+	for _, n := range current {
+		if n != nil {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// First returns the first node selected by q, using nd as the context node.
+// If no node is selected ErrNodeNotFound will be returned.
+func (q *Query) First(nd *Node) (*Node, error) {
+	if r := q.Select(nd); len(r) > 0 {
+		return r[0], nil
+	}
+	return nil, ErrNodeNotFound
+}
+
+// apply applies step s to all nodes in current and returns the selected nodes
+// without duplicates, in the order of Walk.
+func (q *Query) apply(current []*Node, root *Node, s step) []*Node {
+	seen := make(map[*Node]dummyType)
+	result := []*Node{}
+	for _, n := range current {
+		for _, c := range axisNodes(n, root, s.axis) {
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			if q.matches(c, s) {
+				seen[c] = dummy
+				result = append(result, c)
+			}
+		}
+	}
+	if len(result) > 1 {
+		sortNodes(result)
+	}
+	return result
+}
+
+// sortNodes sorts nodes, which must be part of the same tree, in the order of
+// Walk. A nil entry, the virtual parent of the root, comes first.
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return Compare(a, b) < 0
+	})
+}
+
+// matches tells if nd passes the name test and the predicates of s.
+func (q *Query) matches(nd *Node, s step) bool {
+	if nd == nil {
+		return s.name == "*" && len(s.preds) == 0
+	}
+	if s.name != "*" && q.label(nd) != s.name {
+		return false
+	}
+	for _, p := range s.preds {
+		if p.attr == "" {
+			if i, err := nd.Index(); err != nil || i != p.index {
+				return false
+			}
+			continue
+		}
+		v, ok := q.attr(nd, p.attr)
+		switch {
+		case !ok:
+			return false
+		case p.op == "=" && v != p.value:
+			return false
+		case p.op == "!=" && v == p.value:
+			return false
+		}
+	}
+	return true
+}
+
+// label returns nd's label.
+func (q *Query) label(nd *Node) string {
+	if q.Label != nil {
+		return q.Label(nd)
+	}
+	return fmt.Sprint(nd.Data)
+}
+
+// attr returns the value of nd's attribute name.
+func (q *Query) attr(nd *Node, name string) (string, bool) {
+	if q.Attr != nil {
+		return q.Attr(nd, name)
+	}
+	if name == "data" {
+		return fmt.Sprint(nd.Data), true
+	}
+	return "", false
+}
+
+// axisNodes returns the nodes on axis a for nd. A nil nd is the virtual
+// parent of root.
+func axisNodes(nd, root *Node, a axis) []*Node {
+	var r []*Node
+	collect := func(node *Node, data interface{}) {
+		r = append(r, node)
+	}
+
+	switch a {
+	case axisSelf:
+		return []*Node{nd}
+
+	case axisChild:
+		if nd == nil {
+			return []*Node{root}
+		}
+		return nd.Siblings()
+
+	case axisDescendant, axisDescendantOrSelf:
+		if a == axisDescendantOrSelf || nd == nil {
+			r = append(r, nd)
+		}
+		if nd == nil {
+			root.Walk(collect, nil)
+			return r
+		}
+		for _, sbl := range nd.Siblings() {
+			sbl.Walk(collect, nil)
+		}
+		return r
+
+	case axisParent:
+		if nd != nil && nd.parent != nil {
+			return []*Node{nd.parent}
+		}
+
+	case axisAncestor:
+		if nd != nil {
+			return nd.Ancestors()
+		}
+
+	case axisFollowingSibling, axisPrecedingSibling:
+		if nd == nil || nd.parent == nil {
+			return nil
+		}
+		i, err := nd.Index()
+		if err != nil {
+			return nil
+		}
+		sblngs := nd.parent.Siblings()
+		if a == axisFollowingSibling {
+			return sblngs[i+1:]
+		}
+		return sblngs[:i]
+	}
+	return r
+}
+
+// parser is a simple recursive descent parser for path expressions.
+type parser struct {
+	expr string
+	pos  int
+}
+
+// errorf returns a *SyntaxError for the current position.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Expr: p.expr, Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) atEnd() bool {
+	return p.pos >= len(p.expr)
+}
+
+// peek tells if the remaining input starts with s. If so, s is consumed.
+func (p *parser) peek(s string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// step parses a single step.
+func (p *parser) step() (step, error) {
+	s := step{axis: axisChild}
+
+	switch {
+	case p.peek(".."):
+		s.axis, s.name = axisParent, "*"
+	case p.peek("."):
+		s.axis, s.name = axisSelf, "*"
+	default:
+		start := p.pos
+		name, err := p.name()
+		if err != nil {
+			return s, err
+		}
+		if p.peek("::") {
+			a, ok := axisNames[name]
+			if !ok {
+				p.pos = start
+				return s, p.errorf("unknown axis %q", name)
+			}
+			s.axis = a
+			if name, err = p.name(); err != nil {
+				return s, err
+			}
+		}
+		s.name = name
+	}
+
+	for p.peek("[") {
+		pr, err := p.predicate()
+		if err != nil {
+			return s, err
+		}
+		s.preds = append(s.preds, pr)
+	}
+	return s, nil
+}
+
+// name parses a name test: "*", a quoted string or a bare name.
+func (p *parser) name() (string, error) {
+	if p.peek("*") {
+		return "*", nil
+	}
+	if !p.atEnd() && (p.expr[p.pos] == '"' || p.expr[p.pos] == '\'') {
+		return p.quoted()
+	}
+
+	start := p.pos
+	for !p.atEnd() && !strings.ContainsRune("/[]*@=!\"' ", rune(p.expr[p.pos])) {
+		if strings.HasPrefix(p.expr[p.pos:], "::") {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("name expected")
+	}
+	return p.expr[start:p.pos], nil
+}
+
+// quoted parses a string between single or double quotes.
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	q := p.expr[p.pos]
+	end := strings.IndexByte(p.expr[p.pos+1:], q)
+	if end < 0 {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos += end + 2
+	return p.expr[start+1 : p.pos-1], nil
+}
+
+// predicate parses a predicate. The opening bracket is already consumed.
+func (p *parser) predicate() (predicate, error) {
+	var pr predicate
+
+	if p.peek("@") {
+		attr, err := p.name()
+		if err != nil {
+			return pr, err
+		}
+		pr.attr = attr
+		switch {
+		case p.peek("="):
+			pr.op = "="
+		case p.peek("!="):
+			pr.op = "!="
+		}
+		if pr.op != "" {
+			if p.atEnd() || (p.expr[p.pos] != '"' && p.expr[p.pos] != '\'') {
+				return pr, p.errorf("quoted string expected")
+			}
+			if pr.value, err = p.quoted(); err != nil {
+				return pr, err
+			}
+		}
+	} else {
+		start := p.pos
+		for !p.atEnd() && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+			p.pos++
+		}
+		if p.pos == start {
+			return pr, p.errorf("index or attribute expected")
+		}
+		i, err := strconv.Atoi(p.expr[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return pr, p.errorf("invalid index")
+		}
+		pr.index = i
+	}
+
+	if !p.peek("]") {
+		return pr, p.errorf("']' expected")
+	}
+	return pr, nil
+}
@@ -0,0 +1,149 @@
+package otree
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// queryTree returns the tree
+// root[a[item[leaf] item[leaf] item[leaf x]] b[item item item[x]]]
+func queryTree() *Node {
+	root := New("root")
+	a, b := New("a"), New("b")
+	root.Link(AtEnd, a, b)
+	for i := 0; i < 3; i++ {
+		item := New("item")
+		a.Link(AtEnd, item)
+		item.Link(AtEnd, New("leaf"))
+		b.Link(AtEnd, New("item"))
+	}
+	a.siblings[2].Link(AtEnd, New("x"))
+	b.siblings[2].Link(AtEnd, New("x"))
+	return root
+}
+
+func nodesString(nodes []*Node) string {
+	s := "["
+	sep := ""
+	for _, nd := range nodes {
+		s += fmt.Sprintf("%s%v", sep, nd.Data)
+		if p := nd.parent; p != nil {
+			s += fmt.Sprintf("@%v", p.Data)
+		}
+		sep = " "
+	}
+	return s + "]"
+}
+
+func TestQuerySelect(t *testing.T) {
+	root := queryTree()
+	a := root.siblings[0]
+	item := a.siblings[1]
+
+	tests := []struct {
+		expr    string
+		context *Node
+		want    string
+	}{
+		{"/", item, "[root]"},
+		{"/root", item, "[root]"},
+		{"/a", root, "[]"},
+		{"a", root, "[a@root]"},
+		{"/root/*", item, "[a@root b@root]"},
+		{"/root/*/item[2]", root, "[item@a item@b]"},
+		{"/root/*/item[2]/*", root, "[leaf@item x@item x@item]"},
+		{"/root/*/item[2]//leaf", root, "[leaf@item]"},
+		{"//x", root, "[x@item x@item]"},
+		{"//item[0]/leaf", root, "[leaf@item]"},
+		{"//leaf[@data=\"leaf\"]", root, "[leaf@item leaf@item leaf@item]"},
+		{"//*[@data!='item'][0]", root, "[a@root leaf@item leaf@item leaf@item x@item]"},
+		{"//*[@other]", root, "[]"},
+		{"descendant::x", a, "[x@item]"},
+		{"..", item, "[a@root]"},
+		{"./..", item, "[a@root]"},
+		{"ancestor::*", item, "[root a@root]"},
+		{"//x/ancestor::*", root, "[root a@root item@a b@root item@b]"},
+		{"//item[2]/..", root, "[a@root b@root]"},
+		{"..[0]", item, "[a@root]"},
+		{"..[1]", item, "[]"},
+		{".[1]", item, "[item@a]"},
+		{".[@data='leaf']", item, "[]"},
+		{"parent::*[0]", item, "[a@root]"},
+		{"parent::root", item, "[]"},
+		{"following-sibling::*", item, "[item@a]"},
+		{"preceding-sibling::item", item, "[item@a]"},
+		{"self::item", item, "[item@a]"},
+		{"\"item\"[1]/leaf", a, "[leaf@item]"},
+	}
+
+	for _, tst := range tests {
+		q, err := Compile(tst.expr)
+		if err != nil {
+			t.Errorf("Compile(%q) returns error %q, should be nil", tst.expr, err.Error())
+			continue
+		}
+		if got := nodesString(q.Select(tst.context)); got != tst.want {
+			t.Errorf("Select(%q) returns %s, should be %s", tst.expr, got, tst.want)
+		}
+	}
+}
+
+func TestQueryLabelAndAttr(t *testing.T) {
+	root := New(0)
+	root.Link(AtEnd, New(1), New(2), New(3))
+
+	q, err := Compile("/even/odd[@value='3']")
+	if err != nil {
+		t.Fatalf("Compile() returns error %q, should be nil", err.Error())
+	}
+	q.Label = func(nd *Node) string {
+		if nd.Data.(int)%2 == 0 {
+			return "even"
+		}
+		return "odd"
+	}
+	q.Attr = func(nd *Node, name string) (string, bool) {
+		return fmt.Sprint(nd.Data), name == "value"
+	}
+
+	got, err := q.First(root)
+	if err != nil {
+		t.Errorf("First() returns error %q, should be nil", err.Error())
+	} else if got != root.siblings[2] {
+		t.Errorf("First() returns %q, should be %q", got.String(), "3")
+	}
+
+	q.Attr = nil
+	if _, err := q.First(root); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("First() returns error %v, should be %q", err, ErrNodeNotFound)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{"", 0},
+		{"a/", 2},
+		{"a[", 2},
+		{"a[1", 3},
+		{"a[x]", 2},
+		{"a[@data=x]", 8},
+		{"a[@data='x]", 8},
+		{"sideways::a", 0},
+		{"a/[1]", 2},
+		{"a]", 1},
+	}
+
+	for _, tst := range tests {
+		_, err := Compile(tst.expr)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Compile(%q) returns error %v, should be a *SyntaxError", tst.expr, err)
+		} else if se.Pos != tst.pos {
+			t.Errorf("Compile(%q) reports position %d, should be %d", tst.expr, se.Pos, tst.pos)
+		}
+	}
+}
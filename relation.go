@@ -0,0 +1,129 @@
+package otree
+
+// IsAncestorOf tells if nd is an ancestor of node, i.e. if node is part of
+// nd's subtree and isn't nd itself.
+func (nd *Node) IsAncestorOf(node *Node) bool {
+	return node != nd && isInSubtree(node, nd)
+}
+
+// IsDescendantOf tells if nd is a descendant of node, i.e. if nd is part of
+// node's subtree and isn't node itself.
+func (nd *Node) IsDescendantOf(node *Node) bool {
+	return node.IsAncestorOf(nd)
+}
+
+// InSameTree tells if nd and node are part of the same tree.
+func (nd *Node) InSameTree(node *Node) bool {
+	return nd.Root() == node.Root()
+}
+
+// Compare compares the positions of a and b in the order of Walk. It returns
+// -1 if a comes before b, 0 if they are the same node and +1 if a comes after
+// b, so it can be used for sorting. Nodes of different trees have no order,
+// then Compare panics with ErrNodesNotInSameTree. Use CompareNodes when the
+// nodes may be part of different trees.
+func Compare(a, b *Node) int {
+	c, err := CompareNodes(a, b)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// CompareNodes is like Compare, but if a and b are not in the same tree
+// ErrNodesNotInSameTree will be returned. The positions are found without
+// building the paths to the root.
+func CompareNodes(a, b *Node) (int, error) {
+	if a == b {
+		return 0, nil
+	}
+
+	la, lb := a.Level(), b.Level()
+	for ; la > lb; la-- {
+		if a = a.parent; a == b {
+			return 1, nil // b is an ancestor of a
+		}
+	}
+	for ; lb > la; lb-- {
+		if b = b.parent; b == a {
+			return -1, nil // a is an ancestor of b
+		}
+	}
+
+	for a.parent != b.parent {
+		a, b = a.parent, b.parent
+	}
+	p := a.parent
+	if p == nil {
+		return 0, ErrNodesNotInSameTree
+	}
+
+	if p.list != nil {
+		for n := a.next; n != nil; n = n.next {
+			if n == b {
+				return -1, nil
+			}
+		}
+		return 1, nil
+	}
+	ia, _ := a.Index()
+	ib, _ := b.Index()
+	if ia < ib {
+		return -1, nil
+	}
+	return 1, nil
+}
+
+// OrderIndex holds the positions of the nodes of a tree in the order of Walk,
+// so they can be compared in constant time. It isn't updated when the tree
+// changes, Rebuild must be called then.
+type OrderIndex struct {
+	root *Node
+	pos  map[*Node]int
+}
+
+// NewOrderIndex returns an OrderIndex for the tree starting at the root of
+// node.
+func NewOrderIndex(node *Node) *OrderIndex {
+	oi := &OrderIndex{root: node.Root()}
+	oi.Rebuild()
+	return oi
+}
+
+// Rebuild determines the positions of the nodes again.
+func (oi *OrderIndex) Rebuild() {
+	oi.root = oi.root.Root()
+	oi.pos = make(map[*Node]int)
+	n := 0
+	oi.root.walk(func(nd *Node, level int) bool {
+		oi.pos[nd] = n
+		n++
+		return true
+	})
+}
+
+// Position returns the zero-based position of nd in the order of Walk. If nd
+// isn't indexed ErrNodeNotFound will be returned.
+func (oi *OrderIndex) Position(nd *Node) (int, error) {
+	if i, ok := oi.pos[nd]; ok {
+		return i, nil
+	}
+	return -1, ErrNodeNotFound
+}
+
+// Compare is like the function Compare, but it uses the indexed positions.
+// Nodes that aren't indexed, like the ones linked after the last Rebuild, are
+// compared by the function Compare.
+func (oi *OrderIndex) Compare(a, b *Node) int {
+	ia, errA := oi.Position(a)
+	ib, errB := oi.Position(b)
+	switch {
+	case errA != nil || errB != nil:
+		return Compare(a, b)
+	case ia < ib:
+		return -1
+	case ia > ib:
+		return 1
+	}
+	return 0
+}
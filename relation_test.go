@@ -0,0 +1,134 @@
+package otree
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestRelations(t *testing.T) {
+	root := transformTree()
+	one, four := root.siblings[0], root.siblings[0].siblings[1]
+	other := New("other")
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"root.IsAncestorOf(four)", root.IsAncestorOf(four), true},
+		{"one.IsAncestorOf(four)", one.IsAncestorOf(four), true},
+		{"four.IsAncestorOf(one)", four.IsAncestorOf(one), false},
+		{"one.IsAncestorOf(one)", one.IsAncestorOf(one), false},
+		{"four.IsDescendantOf(root)", four.IsDescendantOf(root), true},
+		{"root.IsDescendantOf(four)", root.IsDescendantOf(four), false},
+		{"four.IsDescendantOf(four)", four.IsDescendantOf(four), false},
+		{"four.InSameTree(root)", four.InSameTree(root), true},
+		{"four.InSameTree(other)", four.InSameTree(other), false},
+	}
+	for _, tst := range tests {
+		if tst.got != tst.want {
+			t.Errorf("%s returns %t, should be %t", tst.name, tst.got, tst.want)
+		}
+	}
+}
+
+// comparePanic returns the value compare panics with, or nil.
+func comparePanic(compare func(a, b *Node) int, a, b *Node) (r interface{}) {
+	defer func() { r = recover() }()
+	compare(a, b)
+	return nil
+}
+
+func TestCompare(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := transformTree()
+		if list {
+			root.Walk(func(nd *Node, data interface{}) { nd.SetListStorage(true) }, nil)
+		}
+
+		var nodes []*Node
+		root.Walk(func(nd *Node, data interface{}) { nodes = append(nodes, nd) }, nil)
+		oi := NewOrderIndex(nodes[3])
+
+		for i, a := range nodes {
+			for j, b := range nodes {
+				want := 0
+				if i < j {
+					want = -1
+				} else if i > j {
+					want = 1
+				}
+				if got := Compare(a, b); got != want {
+					t.Errorf("list %t: Compare(%v, %v) returns %d, should be %d",
+						list, a.Data, b.Data, got, want)
+				}
+				if got, err := CompareNodes(a, b); err != nil || got != want {
+					t.Errorf("list %t: CompareNodes(%v, %v) returns %d, %v, should be %d",
+						list, a.Data, b.Data, got, err, want)
+				}
+				if got := oi.Compare(a, b); got != want {
+					t.Errorf("list %t: OrderIndex.Compare(%v, %v) returns %d, should be %d",
+						list, a.Data, b.Data, got, want)
+				}
+			}
+		}
+
+		other := transformTree()
+		if r := comparePanic(Compare, nodes[2], other.siblings[0]); r != ErrNodesNotInSameTree {
+			t.Errorf("list %t: Compare() panics with %v, should be %q", list, r, ErrNodesNotInSameTree)
+		}
+		if r := comparePanic(Compare, root, other); r != ErrNodesNotInSameTree {
+			t.Errorf("list %t: Compare() panics with %v, should be %q", list, r, ErrNodesNotInSameTree)
+		}
+		if _, err := CompareNodes(root, other); err != ErrNodesNotInSameTree {
+			t.Errorf("list %t: CompareNodes() returns error %v, should be %q", list, err, ErrNodesNotInSameTree)
+		}
+		if r := comparePanic(oi.Compare, root, other); r != ErrNodesNotInSameTree {
+			t.Errorf("list %t: OrderIndex.Compare() panics with %v, should be %q", list, r, ErrNodesNotInSameTree)
+		}
+
+		nd := New(7)
+		root.Link(AtStart, nd)
+		if oi.Compare(nd, root) != 1 || oi.Compare(nd, nodes[1]) != -1 {
+			t.Errorf("list %t: OrderIndex.Compare() doesn't compare a node linked after Rebuild()", list)
+		}
+		if _, err := oi.Position(nd); err != ErrNodeNotFound {
+			t.Errorf("list %t: Position() returns error %v, should be %q", list, err, ErrNodeNotFound)
+		}
+		oi.Rebuild()
+		if p, err := oi.Position(nd); err != nil || p != 1 {
+			t.Errorf("list %t: Position() after Rebuild() returns %d, %v, should be 1", list, p, err)
+		}
+	}
+}
+
+func TestCompareSort(t *testing.T) {
+	root := transformTree()
+	nodes := []*Node{root.siblings[1], root.siblings[0].siblings[1], root}
+	sort.Slice(nodes, func(i, j int) bool { return Compare(nodes[i], nodes[j]) < 0 })
+	if got := nodesString(nodes); got != "[0 4@1 2@0]" {
+		t.Errorf("sorting by Compare() results in %s, should be %s", got, "[0 4@1 2@0]")
+	}
+
+	// a sort over nodes of different trees stops with an error
+	nodes = append(nodes, New("other"))
+	var err error
+	sort.Slice(nodes, func(i, j int) bool {
+		c, e := CompareNodes(nodes[i], nodes[j])
+		if e != nil && err == nil {
+			err = e
+		}
+		return c < 0
+	})
+	if err != ErrNodesNotInSameTree {
+		t.Errorf("sorting by CompareNodes() returns error %v, should be %q", err, ErrNodesNotInSameTree)
+	}
+	r := func() (r interface{}) {
+		defer func() { r = recover() }()
+		sort.Slice(nodes, func(i, j int) bool { return Compare(nodes[i], nodes[j]) < 0 })
+		return nil
+	}()
+	if r != ErrNodesNotInSameTree {
+		t.Errorf("sorting by Compare() panics with %v, should be %q", r, ErrNodesNotInSameTree)
+	}
+}
@@ -0,0 +1,139 @@
+package otree
+
+import "sync"
+
+// SafeTree is a tree that can be used concurrently by multiple goroutines.
+// Read operations are performed under a read lock, mutations under a write
+// lock. Nodes returned by its methods must only be inspected or modified
+// within Read or Update.
+type SafeTree struct {
+	mu   sync.RWMutex
+	root *Node
+}
+
+// NewSafeTree returns a SafeTree that owns the tree starting at the root of
+// node. After this call the tree must only be accessed through the SafeTree.
+func NewSafeTree(node *Node) *SafeTree {
+	return &SafeTree{root: node.Root()}
+}
+
+// Read calls f with the root of st under a read lock. f must not modify the
+// tree.
+func (st *SafeTree) Read(f func(root *Node)) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	f(st.root)
+}
+
+// Update calls f with the root of st under a write lock and returns the error
+// returned by f.
+func (st *SafeTree) Update(f func(root *Node) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return f(st.root)
+}
+
+// Breadth returns the breadth of st.
+func (st *SafeTree) Breadth() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return Breadth(st.root, false)
+}
+
+// Degree returns the degree of st.
+func (st *SafeTree) Degree() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return Degree(st.root, false)
+}
+
+// Find returns the first node in st for which f returns true. See
+// Node.Find().
+func (st *SafeTree) Find(f func(node *Node) bool) (*Node, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.root.Find(f)
+}
+
+// Height returns the height of st.
+func (st *SafeTree) Height() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return Height(st.root, false)
+}
+
+// Path returns the path from start to end. See Node.Path().
+func (st *SafeTree) Path(start, end *Node) ([]*Node, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return start.Path(end)
+}
+
+// Size returns the size of st.
+func (st *SafeTree) Size() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return Size(st.root, false)
+}
+
+// Stats returns the statistics of st.
+func (st *SafeTree) Stats() TreeStats {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return Stats(st.root, false)
+}
+
+// String returns the string representation of st.
+func (st *SafeTree) String() string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.root.String()
+}
+
+// Walk executes f for all nodes in st. See Node.Walk(). f must not modify
+// the tree.
+func (st *SafeTree) Walk(f WalkFunc, data interface{}) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	st.root.Walk(f, data)
+}
+
+// Width returns the width of st for level.
+func (st *SafeTree) Width(level int) int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return Width(st.root, level, false)
+}
+
+// Link links nodes to parent. See Node.Link(). If parent is not part of st
+// ErrNodesNotInSameTree will be returned.
+func (st *SafeTree) Link(parent *Node, index int, nodes ...*Node) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if parent.Root() != st.root {
+		return ErrNodesNotInSameTree
+	}
+	return parent.Link(index, nodes...)
+}
+
+// Remove removes nd from st. See Node.Remove(). If nd is not part of st
+// ErrNodesNotInSameTree will be returned.
+func (st *SafeTree) Remove(nd *Node) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if nd.Root() != st.root {
+		return ErrNodesNotInSameTree
+	}
+	return nd.Remove()
+}
+
+// Replace replaces nd by nodes. See Node.Replace(). If nd is not part of st
+// ErrNodesNotInSameTree will be returned.
+func (st *SafeTree) Replace(nd *Node, nodes ...*Node) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if nd.Root() != st.root {
+		return ErrNodesNotInSameTree
+	}
+	return nd.Replace(nodes...)
+}
@@ -0,0 +1,88 @@
+package otree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSafeTreeConcurrentUse(t *testing.T) {
+	root := New(0)
+	st := NewSafeTree(root)
+
+	const n = 200
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= n; i++ {
+			nd := New(i)
+			if err := st.Link(root, AtEnd, nd); err != nil {
+				t.Errorf("Link() returns error %q, should be nil", err.Error())
+			}
+			if i%2 == 0 {
+				if err := st.Remove(nd); err != nil {
+					t.Errorf("Remove() returns error %q, should be nil", err.Error())
+				}
+			} else if i%3 == 0 {
+				if err := st.Replace(nd, New(-i)); err != nil {
+					t.Errorf("Replace() returns error %q, should be nil", err.Error())
+				}
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				st.Size()
+				st.Height()
+				st.Breadth()
+				st.Degree()
+				st.Width(1)
+				st.Stats()
+				_ = st.String()
+				st.Walk(func(nd *Node, data interface{}) { _ = nd.Data }, nil)
+				if nd, err := st.Find(func(nd *Node) bool { return nd.Data == 1 }); err == nil {
+					st.Path(nd, root)
+				}
+				st.Read(func(root *Node) {
+					for _, sbl := range root.Siblings() {
+						_ = sbl.Data
+					}
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := st.Size(), 1+n/2; got != want {
+		t.Errorf("Size() returns %d, should be %d", got, want)
+	}
+}
+
+func TestSafeTreeUpdate(t *testing.T) {
+	st := NewSafeTree(New("root"))
+	err := st.Update(func(root *Node) error {
+		return root.Link(AtEnd, New("s0"), root)
+	})
+	if err != ErrDuplicateNodeFound {
+		t.Errorf("Update() returns error %v, should be %q", err, ErrDuplicateNodeFound)
+	}
+
+	other := New("other")
+	if err := st.Link(other, AtEnd, New("s1")); err != ErrNodesNotInSameTree {
+		t.Errorf("Link() returns error %v, should be %q", err, ErrNodesNotInSameTree)
+	}
+	if err := st.Remove(other); err != ErrNodesNotInSameTree {
+		t.Errorf("Remove() returns error %v, should be %q", err, ErrNodesNotInSameTree)
+	}
+	if err := st.Replace(other, New("s2")); err != ErrNodesNotInSameTree {
+		t.Errorf("Replace() returns error %v, should be %q", err, ErrNodesNotInSameTree)
+	}
+	if got := st.String(); got != "root" {
+		t.Errorf("String() returns %q, should be %q", got, "root")
+	}
+}
@@ -0,0 +1,102 @@
+package otree
+
+import "sort"
+
+// LessFunc is a function type that tells if node a must be ordered before
+// node b.
+type LessFunc func(a, b *Node) bool
+
+// SortChildren sorts nd's siblings by less. The sort is not guaranteed to be
+// stable.
+func (nd *Node) SortChildren(less LessFunc) {
+	nd.reorder(func(s []*Node) {
+		sort.Slice(s, func(i, j int) bool { return less(s[i], s[j]) })
+	})
+	nd.childrenChanged()
+}
+
+// SortChildrenStable sorts nd's siblings by less, keeping the order of equal
+// siblings.
+func (nd *Node) SortChildrenStable(less LessFunc) {
+	nd.reorder(func(s []*Node) {
+		sort.SliceStable(s, func(i, j int) bool { return less(s[i], s[j]) })
+	})
+	nd.childrenChanged()
+}
+
+// SortTree sorts the siblings of nd and all of its descendants by less,
+// keeping the order of equal siblings.
+func (nd *Node) SortTree(less LessFunc) {
+	nd.walk(func(node *Node, level int) bool {
+		if node.Degree() > 1 {
+			node.reorder(func(s []*Node) {
+				sort.SliceStable(s, func(i, j int) bool { return less(s[i], s[j]) })
+			})
+		}
+		return true
+	})
+	nd.subtreeChanged()
+}
+
+// ReverseChildren reverses the order of nd's siblings.
+func (nd *Node) ReverseChildren() {
+	nd.reorder(func(s []*Node) {
+		invertSlice(s)
+	})
+	nd.childrenChanged()
+}
+
+// SwapChildren swaps nd's siblings with indexes i and j. If one of them
+// doesn't exist ErrNodeNotFound will be returned.
+func (nd *Node) SwapChildren(i, j int) error {
+	if d := nd.Degree(); i < 0 || i >= d || j < 0 || j >= d {
+		return ErrNodeNotFound
+	}
+	nd.reorder(func(s []*Node) {
+		s[i], s[j] = s[j], s[i]
+	})
+	nd.childrenChanged()
+	return nil
+}
+
+// MoveChild moves nd's sibling with index from, so its index becomes to. The
+// siblings in between shift one position. If one of the indexes is out of
+// range ErrNodeNotFound will be returned.
+func (nd *Node) MoveChild(from, to int) error {
+	if d := nd.Degree(); from < 0 || from >= d || to < 0 || to >= d {
+		return ErrNodeNotFound
+	}
+	nd.reorder(func(s []*Node) {
+		moved := s[from]
+		if from < to {
+			copy(s[from:to], s[from+1:to+1])
+		} else {
+			copy(s[to+1:from+1], s[to:from])
+		}
+		s[to] = moved
+	})
+	nd.childrenChanged()
+	return nil
+}
+
+// reorder lets f rearrange a copy of nd's siblings and stores the result as
+// nd's siblings. The parents of the siblings are not changed. A slice
+// returned earlier by Siblings() is never changed.
+func (nd *Node) reorder(f func(s []*Node)) {
+	if nd.IsLeaf() {
+		return
+	}
+	if nd.list != nil {
+		s := nd.Siblings()
+		f(s)
+		nd.list = &siblingList{}
+		nd.listInsert(nil, s)
+		return
+	}
+
+	s := append([]*Node{}, nd.siblings...)
+	f(s)
+	nd.siblings = s
+	nd.base = 0
+	nd.renumber(0)
+}
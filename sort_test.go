@@ -0,0 +1,96 @@
+package otree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func byData(a, b *Node) bool {
+	return a.Data.(int) < b.Data.(int)
+}
+
+func TestSortChildren(t *testing.T) {
+	for _, list := range []bool{false, true} {
+		root := New(0)
+		root.SetListStorage(list)
+		root.Link(AtEnd, New(3), New(1), New(4), New(1), New(5), New(9), New(2), New(6))
+		old := root.Siblings()
+		ones := []*Node{old[1], old[3]}
+
+		steps := []struct {
+			name string
+			op   func() error
+			want string
+		}{
+			{"SortChildrenStable", func() error { root.SortChildrenStable(byData); return nil }, "0[1 1 2 3 4 5 6 9]"},
+			{"ReverseChildren", func() error { root.ReverseChildren(); return nil }, "0[9 6 5 4 3 2 1 1]"},
+			{"SwapChildren", func() error { return root.SwapChildren(0, 7) }, "0[1 6 5 4 3 2 1 9]"},
+			{"MoveChild", func() error { return root.MoveChild(1, 5) }, "0[1 5 4 3 2 6 1 9]"},
+			{"MoveChild", func() error { return root.MoveChild(6, 0) }, "0[1 1 5 4 3 2 6 9]"},
+			{"MoveChild", func() error { return root.MoveChild(3, 3) }, "0[1 1 5 4 3 2 6 9]"},
+			{"SortChildren", func() error { root.SortChildren(byData); return nil }, "0[1 1 2 3 4 5 6 9]"},
+		}
+		for i, s := range steps {
+			if err := s.op(); err != nil {
+				t.Errorf("list %t: %s() returns error %q, should be nil", list, s.name, err.Error())
+			}
+			if sblngs := root.Siblings(); i == 0 && (sblngs[0] != ones[0] || sblngs[1] != ones[1]) {
+				t.Errorf("list %t: SortChildrenStable() changes the order of equal siblings", list)
+			}
+			if got := root.String(); got != s.want {
+				t.Errorf("list %t: %s() results in %q, should be %q", list, s.name, got, s.want)
+			}
+			for i, sbl := range root.Siblings() {
+				if idx, err := sbl.Index(); err != nil || idx != i || sbl.parent != root {
+					t.Errorf("list %t: %s(): Index() returns %d, %v, should be %d", list, s.name, idx, err, i)
+				}
+			}
+		}
+
+		if got := nodesString(old); got != "[3@0 1@0 4@0 1@0 5@0 9@0 2@0 6@0]" {
+			t.Errorf("list %t: sorting changes a slice returned by Siblings(): %s", list, got)
+		}
+
+		for _, idx := range [][2]int{{-1, 0}, {0, 8}, {8, 0}} {
+			if err := root.SwapChildren(idx[0], idx[1]); err != ErrNodeNotFound {
+				t.Errorf("list %t: SwapChildren(%d, %d) returns error %v, should be %q", list, idx[0], idx[1], err, ErrNodeNotFound)
+			}
+			if err := root.MoveChild(idx[0], idx[1]); err != ErrNodeNotFound {
+				t.Errorf("list %t: MoveChild(%d, %d) returns error %v, should be %q", list, idx[0], idx[1], err, ErrNodeNotFound)
+			}
+		}
+	}
+}
+
+func TestSortTree(t *testing.T) {
+	root := transformTree()
+	order := Aggregate{
+		Name:    "order",
+		Value:   func(nd *Node) interface{} { return fmt.Sprint(nd.Data) },
+		Combine: func(a, b interface{}) interface{} { return a.(string) + " " + b.(string) },
+	}
+	EnableAggregates(root, sumAggregate(), order)
+	greater := func(a, b *Node) bool { return byData(b, a) }
+
+	root.siblings[0].SortTree(greater)
+	if got, want := root.String(), "0[1[4[6] 3] 2[5]]"; got != want {
+		t.Errorf("SortTree() on a subtree results in %q, should be %q", got, want)
+	}
+	checkAggregates(t, "SortTree", root)
+
+	root.SortTree(greater)
+	if got, want := root.String(), "0[2[5] 1[4[6] 3]]"; got != want {
+		t.Errorf("SortTree() results in %q, should be %q", got, want)
+	}
+	checkAggregates(t, "SortTree", root)
+
+	if got, _ := root.Aggregate("order"); got != "0 2 5 1 4 6 3" {
+		t.Errorf("SortTree() results in aggregate %q, should be %q", got, "0 2 5 1 4 6 3")
+	}
+
+	root.siblings[1].ReverseChildren()
+	checkAggregates(t, "ReverseChildren", root)
+	if got, _ := root.Aggregate("order"); got != "0 2 5 1 3 4 6" {
+		t.Errorf("ReverseChildren() results in aggregate %q, should be %q", got, "0 2 5 1 3 4 6")
+	}
+}
@@ -0,0 +1,145 @@
+package otree
+
+// Detach removes nd, with its subtree, from the tree and returns it as the
+// root of a tree of its own. The root node cannot be detached.
+func (nd *Node) Detach() (*Node, error) {
+	if err := nd.Remove(); err != nil {
+		return nil, err
+	}
+	return nd, nil
+}
+
+// Graft links the tree starting at sub to nd, just before the child with
+// index, like Link does. sub must be the root of another tree. If it has a
+// parent ErrNodeHasParent will be returned, if it is the root of nd's tree
+// ErrDuplicateNodeFound. In contrast to Link, the trees aren't searched for
+// duplicate nodes.
+func (nd *Node) Graft(index int, sub *Node) error {
+	nodes := []*Node{sub}
+	if err := nd.checkRoots(nodes); err != nil {
+		return err
+	}
+	nd.link(index, nodes)
+	return nil
+}
+
+// Lift replaces nd by its siblings, in their order. Afterwards nd is a leaf
+// without a parent. The root node cannot be lifted.
+func (nd *Node) Lift() error {
+	p := nd.parent
+	if p == nil {
+		return ErrCannotRemoveRootNode
+	}
+	i, err := nd.Index()
+	if err != nil {
+		return err
+	}
+
+	sblngs := nd.unlinkSiblings()
+	p.RemoveSibling(i)
+	p.link(i, sblngs)
+	nd.childrenChanged()
+	return nil
+}
+
+// Wrap inserts newParent between nd and its parent, so newParent takes nd's
+// place and nd becomes newParent's only sibling. If nd is the root node,
+// newParent becomes the new root. newParent must be a leaf without a parent.
+// If it has a parent ErrNodeHasParent will be returned, if it has siblings
+// ErrNodeMustNotHaveSiblings and if it is nd itself ErrDuplicateNodeFound.
+func (nd *Node) Wrap(newParent *Node) error {
+	switch {
+	case newParent.parent != nil:
+		return ErrNodeHasParent
+	case !newParent.IsLeaf():
+		return ErrNodeMustNotHaveSiblings
+	case newParent == nd:
+		return ErrDuplicateNodeFound
+	}
+
+	p := nd.parent
+	i := 0
+	if p != nil {
+		var err error
+		if i, err = nd.Index(); err != nil {
+			return err
+		}
+		p.RemoveSibling(i)
+	}
+
+	newParent.link(AtEnd, []*Node{nd})
+	if nd.agg != nil {
+		// nd's aggregates are still valid
+		newParent.agg = &aggregates{defs: nd.agg.defs}
+		newParent.computeAggregates()
+	}
+	if p != nil {
+		p.link(i, []*Node{newParent})
+	}
+	return nil
+}
+
+// Flatten limits the height of the subtree starting at nd to depth. All
+// descendants of a node at level depth-1, relative to nd, become siblings of
+// that node, in the order of Walk. They become leaves. So Flatten(1) makes all
+// of nd's descendants its siblings. A depth smaller than 1 is taken as 1.
+func (nd *Node) Flatten(depth int) {
+	if depth < 1 {
+		depth = 1
+	}
+
+	var targets []*Node
+	nd.walk(func(node *Node, level int) bool {
+		if level == depth-1 && !node.IsLeaf() {
+			targets = append(targets, node)
+		}
+		return true
+	})
+
+	for _, t := range targets {
+		var desc []*Node
+		t.walk(func(node *Node, level int) bool {
+			if level > 0 {
+				desc = append(desc, node)
+			}
+			return true
+		})
+		if len(desc) == t.Degree() {
+			continue
+		}
+
+		for _, d := range desc {
+			d.unlinkSiblings()
+		}
+		t.unlinkSiblings()
+		t.attach(desc)
+	}
+	nd.subtreeChanged()
+}
+
+// MergeFunc is a function type that is called by Collapse when child, the only
+// sibling of parent, is merged into parent.
+type MergeFunc func(parent, child *Node)
+
+// Collapse merges the chains of nodes with a single sibling in the subtree
+// starting at nd. When a node has exactly one sibling, that sibling is removed
+// and its siblings become the node's siblings. Before that merge is called,
+// so it can combine the data of both nodes. When merge is nil the data of
+// the node is kept.
+func (nd *Node) Collapse(merge MergeFunc) {
+	stack := []*Node{nd}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for node.Degree() == 1 {
+			child := node.unlinkSiblings()[0]
+			if merge != nil {
+				merge(node, child)
+			}
+			node.attach(child.unlinkSiblings())
+		}
+		stack = append(stack, node.Siblings()...)
+	}
+	nd.subtreeChanged()
+}
@@ -0,0 +1,164 @@
+package otree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDetachAndGraft(t *testing.T) {
+	root := transformTree()
+	EnableAggregates(root, sumAggregate())
+	one := root.siblings[0]
+
+	sub, err := one.Detach()
+	if err != nil || sub != one || one.parent != nil {
+		t.Errorf("Detach() returns %v, %v, should be the node", sub, err)
+	}
+	if got, want := root.String(), "0[2[5]]"; got != want {
+		t.Errorf("Detach() results in %q, should be %q", got, want)
+	}
+	checkAggregates(t, "Detach", root)
+	if _, err := root.Detach(); err != ErrCannotRemoveRootNode {
+		t.Errorf("Detach() returns error %v, should be %q", err, ErrCannotRemoveRootNode)
+	}
+
+	five := root.siblings[0].siblings[0]
+	if err := five.Graft(AtStart, sub); err != nil {
+		t.Errorf("Graft() returns error %q, should be nil", err.Error())
+	}
+	if got, want := root.String(), "0[2[5[1[3 4[6]]]]]"; got != want {
+		t.Errorf("Graft() results in %q, should be %q", got, want)
+	}
+	checkAggregates(t, "Graft", root)
+
+	tests := []struct {
+		sub *Node
+		err error
+	}{
+		{one.siblings[0], ErrNodeHasParent},
+		{root, ErrDuplicateNodeFound},
+	}
+	for i, tst := range tests {
+		if err := one.Graft(AtEnd, tst.sub); err != tst.err {
+			t.Errorf("%d: Graft() returns error %v, should be %q", i, err, tst.err)
+		}
+	}
+}
+
+func TestLiftAndWrap(t *testing.T) {
+	root := transformTree()
+	EnableAggregates(root, sumAggregate())
+	one := root.siblings[0]
+
+	if err := one.Lift(); err != nil {
+		t.Errorf("Lift() returns error %q, should be nil", err.Error())
+	}
+	if got, want := root.String(), "0[3 4[6] 2[5]]"; got != want {
+		t.Errorf("Lift() results in %q, should be %q", got, want)
+	}
+	if one.parent != nil || !one.IsLeaf() {
+		t.Errorf("Lift() leaves %v", one)
+	}
+	checkAggregates(t, "Lift", root)
+	if err := root.Lift(); err != ErrCannotRemoveRootNode {
+		t.Errorf("Lift() returns error %v, should be %q", err, ErrCannotRemoveRootNode)
+	}
+
+	four := root.siblings[1]
+	if err := four.Wrap(one); err != nil {
+		t.Errorf("Wrap() returns error %q, should be nil", err.Error())
+	}
+	if got, want := root.String(), "0[3 1[4[6]] 2[5]]"; got != want {
+		t.Errorf("Wrap() results in %q, should be %q", got, want)
+	}
+	checkAggregates(t, "Wrap", root)
+
+	top := New(7)
+	if err := root.Wrap(top); err != nil {
+		t.Errorf("Wrap() returns error %q, should be nil", err.Error())
+	}
+	if got, want := top.String(), "7[0[3 1[4[6]] 2[5]]]"; got != want {
+		t.Errorf("Wrap() results in %q, should be %q", got, want)
+	}
+	checkAggregates(t, "Wrap root", top)
+
+	tests := []struct {
+		newParent *Node
+		err       error
+	}{
+		{four, ErrNodeHasParent},
+		{transformTree(), ErrNodeMustNotHaveSiblings},
+		{root.siblings[0], ErrNodeHasParent},
+	}
+	for i, tst := range tests {
+		if err := four.Wrap(tst.newParent); err != tst.err {
+			t.Errorf("%d: Wrap() returns error %v, should be %q", i, err, tst.err)
+		}
+	}
+	leaf := New(8)
+	if err := leaf.Wrap(leaf); err != ErrDuplicateNodeFound {
+		t.Errorf("Wrap() returns error %v, should be %q", err, ErrDuplicateNodeFound)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{0, "0[1 3 4 6 2 5]"},
+		{1, "0[1 3 4 6 2 5]"},
+		{2, "0[1[3 4 6] 2[5]]"},
+		{3, "0[1[3 4[6]] 2[5]]"},
+	}
+	for _, list := range []bool{false, true} {
+		for _, tst := range tests {
+			root := transformTree()
+			if list {
+				root.Walk(func(nd *Node, data interface{}) { nd.SetListStorage(true) }, nil)
+			}
+			EnableAggregates(root, sumAggregate())
+
+			root.Flatten(tst.depth)
+			if got := root.String(); got != tst.want {
+				t.Errorf("list %t: Flatten(%d) results in %q, should be %q", list, tst.depth, got, tst.want)
+			}
+			for i, sbl := range root.Siblings() {
+				if idx, err := sbl.Index(); err != nil || idx != i || sbl.parent != root {
+					t.Errorf("list %t: Flatten(%d): Index() returns %d, %v, should be %d", list, tst.depth, idx, err, i)
+				}
+			}
+			checkAggregates(t, fmt.Sprintf("Flatten(%d)", tst.depth), root)
+		}
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	root := New(0)
+	a := New(1)
+	root.Link(AtEnd, a)
+	a.Link(AtEnd, New(2))
+	a.siblings[0].Link(AtEnd, New(3), New(4))
+	a.siblings[0].siblings[1].Link(AtEnd, New(5))
+	root.Link(AtEnd, New(6))
+	EnableAggregates(root, sumAggregate())
+
+	var merged []string
+	root.Collapse(func(parent, child *Node) {
+		merged = append(merged, fmt.Sprintf("%v+%v", parent.Data, child.Data))
+		parent.Data = parent.Data.(int) + child.Data.(int)
+	})
+	if got, want := root.String(), "0[3[3 9] 6]"; got != want {
+		t.Errorf("Collapse() results in %q, should be %q", got, want)
+	}
+	if got, want := fmt.Sprint(merged), "[1+2 4+5]"; got != want {
+		t.Errorf("Collapse() merges %s, should be %s", got, want)
+	}
+	checkAggregates(t, "Collapse", root)
+
+	chain, _ := deepChain(100)
+	chain.Collapse(nil)
+	if got := chain.String(); got != "0" {
+		t.Errorf("Collapse() results in %q, should be %q", got, "0")
+	}
+}
@@ -26,6 +26,14 @@ func invertSlice(nodes []*Node) []*Node {
 	return nodes
 }
 
+// invertInts inverts the sequence of the integers
+func invertInts(a []int) []int {
+	for i, j := 0, len(a)-1; i < j; i, j = i+1, j-1 {
+		a[i], a[j] = a[j], a[i]
+	}
+	return a
+}
+
 // mergePaths merges the up and down paths via the lowest shared node
 func mergePaths(up, down []*Node) ([]*Node, error) {
 	up, down = invertSlice(up), invertSlice(down)
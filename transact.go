@@ -0,0 +1,180 @@
+package otree
+
+// Tx is a transaction on a tree, see Transact. Its methods behave like the
+// corresponding methods of Node, but they check all conditions before
+// changing the tree, so a failing method leaves the tree unchanged.
+type Tx struct {
+	root *Node
+	undo []func()
+}
+
+// Transact calls f with a transaction on the tree holding root. If f returns
+// an error, or panics, all changes made through the transaction are rolled
+// back and the tree is restored to its original state. Otherwise the changes
+// are kept. The error returned by f is returned.
+// Other goroutines must not access the tree during the transaction, use
+// SafeTree.Transact when they might.
+func Transact(root *Node, f func(tx *Tx) error) (err error) {
+	tx := &Tx{root: root.Root()}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+
+	if err = f(tx); err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+// Transact is like the function Transact, but it holds st's write lock during
+// the transaction, so readers never see a partially changed tree.
+func (st *SafeTree) Transact(f func(tx *Tx) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return Transact(st.root, f)
+}
+
+// Link links nodes to parent, like Node.Link().
+func (tx *Tx) Link(parent *Node, index int, nodes ...*Node) error {
+	if err := tx.contains(parent); err != nil {
+		return err
+	}
+	if err := parent.checkLink(nodes); err != nil {
+		return err
+	}
+
+	if d := parent.Degree(); index > d {
+		index = d
+	} else if index < 0 {
+		index = 0
+	}
+	parent.link(index, nodes)
+	tx.undo = append(tx.undo, func() {
+		for range nodes {
+			parent.RemoveSibling(index)
+		}
+	})
+	return nil
+}
+
+// Remove removes nd from the tree, like Node.Remove().
+func (tx *Tx) Remove(nd *Node) error {
+	if err := tx.contains(nd); err != nil {
+		return err
+	}
+	if nd.parent == nil {
+		return ErrCannotRemoveRootNode
+	}
+	i, err := nd.Index()
+	if err != nil {
+		return err
+	}
+	_, err = tx.RemoveSibling(nd.parent, i)
+	return err
+}
+
+// RemoveSibling removes parent's child with index, like
+// Node.RemoveSibling().
+func (tx *Tx) RemoveSibling(parent *Node, index int) (*Node, error) {
+	if err := tx.contains(parent); err != nil {
+		return nil, err
+	}
+	nd, err := parent.RemoveSibling(index)
+	if err != nil {
+		return nil, err
+	}
+	tx.undo = append(tx.undo, func() {
+		parent.link(index, []*Node{nd})
+	})
+	return nd, nil
+}
+
+// RemoveAllSiblings removes all of parent's siblings, like
+// Node.RemoveAllSiblings().
+func (tx *Tx) RemoveAllSiblings(parent *Node) ([]*Node, error) {
+	if err := tx.contains(parent); err != nil {
+		return nil, err
+	}
+	nodes := parent.RemoveAllSiblings()
+	if len(nodes) > 0 {
+		tx.undo = append(tx.undo, func() {
+			parent.link(0, nodes)
+		})
+	}
+	return nodes, nil
+}
+
+// Replace replaces nd by nodes, like Node.Replace().
+func (tx *Tx) Replace(nd *Node, nodes ...*Node) error {
+	if err := tx.contains(nd); err != nil {
+		return err
+	}
+	if nd.parent == nil {
+		return ErrCannotReplaceRootNode
+	}
+	i, err := nd.Index()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ReplaceSibling(nd.parent, i, nodes...)
+	return err
+}
+
+// ReplaceSibling replaces parent's child with index by nodes, like
+// Node.ReplaceSibling().
+func (tx *Tx) ReplaceSibling(parent *Node, index int, nodes ...*Node) (*Node, error) {
+	if err := tx.contains(parent); err != nil {
+		return nil, err
+	}
+	if _, err := parent.Sibling(index); err != nil {
+		return nil, err
+	}
+	if err := parent.checkLink(nodes); err != nil {
+		return nil, err
+	}
+
+	nd, _ := parent.RemoveSibling(index)
+	parent.link(index, nodes)
+	tx.undo = append(tx.undo, func() {
+		for range nodes {
+			parent.RemoveSibling(index)
+		}
+		parent.link(index, []*Node{nd})
+	})
+	return nd, nil
+}
+
+// SetData sets the data of nd.
+func (tx *Tx) SetData(nd *Node, data interface{}) error {
+	if err := tx.contains(nd); err != nil {
+		return err
+	}
+	old := nd.Data
+	nd.Data = data
+	nd.childrenChanged()
+	tx.undo = append(tx.undo, func() {
+		nd.Data = old
+		nd.childrenChanged()
+	})
+	return nil
+}
+
+// rollback undoes all changes in reverse order.
+func (tx *Tx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+// contains checks if nd is part of the transaction's tree. If not,
+// ErrNodesNotInSameTree will be returned.
+func (tx *Tx) contains(nd *Node) error {
+	if nd.Root() != tx.root {
+		return ErrNodesNotInSameTree
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package otree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransactCommit(t *testing.T) {
+	root := New("root")
+	s0 := New("s0")
+	root.Link(AtEnd, s0)
+
+	err := Transact(root, func(tx *Tx) error {
+		if err := tx.Link(root, AtEnd, New("s1"), New("s2")); err != nil {
+			return err
+		}
+		return tx.SetData(s0, "x")
+	})
+	if err != nil {
+		t.Errorf("Transact() returns error %q, should be nil", err.Error())
+	}
+	if got, want := root.String(), "root[x s1 s2]"; got != want {
+		t.Errorf("Transact() results in %q, should be %q", got, want)
+	}
+}
+
+func TestTransactRollback(t *testing.T) {
+	root := New("root")
+	s0, s1, s2 := New("s0"), New("s1"), New("s2")
+	root.Link(AtEnd, s0, s1, s2)
+	s1.Link(AtEnd, New("c0"), New("c1"))
+	EnableAggregates(root)
+	want := root.String()
+
+	var linkErr error
+	err := Transact(s2, func(tx *Tx) error {
+		tx.Link(root, 1, New("a"), New("b"))
+		tx.Remove(s0)
+		tx.RemoveSibling(s1, 0)
+		tx.Replace(s2, New("r0"), New("r1"))
+		tx.RemoveAllSiblings(s1)
+		tx.SetData(root, "top")
+		tx.Link(s0, AtEnd, New("d"))
+		linkErr = tx.Link(root, AtEnd, s1)
+		return linkErr
+	})
+
+	if !errors.Is(err, ErrDuplicateNodeFound) {
+		t.Errorf("Transact() returns error %v, should be %q", err, ErrDuplicateNodeFound)
+	}
+	if got := root.String(); got != want {
+		t.Errorf("Transact() results in %q, should be %q", got, want)
+	}
+	if s0.parent != root || s1.parent != root || s2.parent != root {
+		t.Errorf("Transact() doesn't restore the parents")
+	}
+	if got := Size(root, false); got != 6 {
+		t.Errorf("Size() after rollback returns %d, should be 6", got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Transact() doesn't propagate a panic")
+			}
+		}()
+		Transact(root, func(tx *Tx) error {
+			tx.Remove(s1)
+			panic("oops")
+		})
+	}()
+	if got := root.String(); got != want {
+		t.Errorf("Transact() with a panic results in %q, should be %q", got, want)
+	}
+}
+
+func TestTxErrors(t *testing.T) {
+	root := New("root")
+	s0 := New("s0")
+	root.Link(AtEnd, s0)
+	other := New("other")
+
+	Transact(root, func(tx *Tx) error {
+		tests := []struct {
+			op  func() error
+			err error
+		}{
+			{func() error { return tx.Link(other, AtEnd, New("x")) }, ErrNodesNotInSameTree},
+			{func() error { return tx.Link(root, AtEnd, s0) }, ErrDuplicateNodeFound},
+			{func() error { return tx.Remove(root) }, ErrCannotRemoveRootNode},
+			{func() error { return tx.Replace(root, New("x")) }, ErrCannotReplaceRootNode},
+			{func() error { _, err := tx.RemoveSibling(root, 1); return err }, ErrNodeNotFound},
+			{func() error { _, err := tx.ReplaceSibling(root, 0, root); return err }, ErrDuplicateNodeFound},
+			{func() error { _, err := tx.RemoveAllSiblings(other); return err }, ErrNodesNotInSameTree},
+			{func() error { return tx.SetData(other, "x") }, ErrNodesNotInSameTree},
+		}
+		for i, tst := range tests {
+			if err := tst.op(); err != tst.err {
+				t.Errorf("%d: returns error %v, should be %q", i, err, tst.err)
+			}
+		}
+		return nil
+	})
+	if got := root.String(); got != "root[s0]" {
+		t.Errorf("failing operations result in %q, should be %q", got, "root[s0]")
+	}
+}
+
+func TestSafeTreeTransact(t *testing.T) {
+	st := NewSafeTree(New("root"))
+	errAbort := errors.New("abort")
+	err := st.Transact(func(tx *Tx) error {
+		tx.Link(st.root, AtEnd, New("s0"))
+		return errAbort
+	})
+	if err != errAbort || st.String() != "root" {
+		t.Errorf("Transact() returns %v and results in %q", err, st.String())
+	}
+}
@@ -0,0 +1,93 @@
+package otree
+
+// FilterMode tells Filter what to do with the children of nodes that are not
+// kept.
+type FilterMode int
+
+const (
+	DropSubtree     FilterMode = iota // a dropped node takes its subtree with it
+	PromoteChildren                   // the kept descendants of a dropped node take its place
+)
+
+// MapFunc is a function type that returns the data for the copy of nd.
+type MapFunc func(nd *Node) interface{}
+
+// FoldFunc is a function type that combines nd with the results for its
+// siblings. childResults holds these results in the order of the siblings.
+type FoldFunc func(nd *Node, childResults []interface{}) interface{}
+
+// Map returns a new tree with the same structure as the (sub)tree starting at
+// root. The data of each new node is the result of calling f for the
+// corresponding node in the original tree. The original tree is not modified.
+func Map(root *Node, f MapFunc) *Node {
+	nd := New(f(root))
+	sblngs := root.Siblings()
+	if len(sblngs) > 0 {
+		nodes := make([]*Node, len(sblngs))
+		for i, sbl := range sblngs {
+			nodes[i] = Map(sbl, f)
+		}
+		nd.adopt(nodes...)
+	}
+	return nd
+}
+
+// Filter returns a new tree holding copies of the nodes of the (sub)tree
+// starting at root for which keep returns true. The copies share their data
+// with the originals. mode decides what happens to the descendants of nodes
+// that are not kept. If root itself is not kept, Filter returns nil, even
+// when mode is PromoteChildren and some of root's descendants are kept. Use
+// FilterForest to get these. The original tree is not modified.
+func Filter(root *Node, keep func(*Node) bool, mode FilterMode) *Node {
+	nodes, kept := filterNodes(root, keep, mode)
+	if !kept {
+		return nil
+	}
+	return nodes[0]
+}
+
+// FilterForest is like Filter, but returns the roots of the filtered trees.
+// That is the copy of root when root is kept. Otherwise, when mode is
+// PromoteChildren, the kept descendants of root that take its place.
+func FilterForest(root *Node, keep func(*Node) bool, mode FilterMode) []*Node {
+	nodes, _ := filterNodes(root, keep, mode)
+	return nodes
+}
+
+// filterNodes returns the nodes that replace nd in the filtered tree and tells
+// if nd is kept. keep is called once for every visited node.
+func filterNodes(nd *Node, keep func(*Node) bool, mode FilterMode) ([]*Node, bool) {
+	kept := keep(nd)
+	if !kept && mode == DropSubtree {
+		return nil, false
+	}
+
+	var nodes []*Node
+	for _, sbl := range nd.Siblings() {
+		n, _ := filterNodes(sbl, keep, mode)
+		nodes = append(nodes, n...)
+	}
+	if !kept {
+		return nodes, false
+	}
+
+	cp := New(nd.Data)
+	if len(nodes) > 0 {
+		cp.adopt(nodes...)
+	}
+	return []*Node{cp}, true
+}
+
+// Fold combines the nodes of the (sub)tree starting at root bottom-up. f is
+// called for every node with the results of f for its siblings, the result
+// for root is returned.
+func Fold(root *Node, f FoldFunc) interface{} {
+	var results []interface{}
+	if sblngs := root.Siblings(); len(sblngs) > 0 {
+		results = make([]interface{}, len(sblngs))
+		for i, sbl := range sblngs {
+			results[i] = Fold(sbl, f)
+		}
+	}
+	return f(root, results)
+}
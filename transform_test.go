@@ -0,0 +1,117 @@
+package otree
+
+import (
+	"testing"
+)
+
+// transformTree returns the tree 0[1[3 4[6]] 2[5]]
+func transformTree() *Node {
+	nodes := make([]*Node, 7)
+	for i := range nodes {
+		nodes[i] = New(i)
+	}
+	nodes[0].Link(AtEnd, nodes[1], nodes[2])
+	nodes[1].Link(AtEnd, nodes[3], nodes[4])
+	nodes[2].Link(AtEnd, nodes[5])
+	nodes[4].Link(AtEnd, nodes[6])
+	return nodes[0]
+}
+
+func TestMap(t *testing.T) {
+	root := transformTree()
+	want := root.String()
+
+	got := Map(root, func(nd *Node) interface{} {
+		return nd.Data.(int) * 10
+	})
+	if s := got.String(); s != "0[10[30 40[60]] 20[50]]" {
+		t.Errorf("Map() returns %q, should be %q", s, "0[10[30 40[60]] 20[50]]")
+	}
+	if s := root.String(); s != want {
+		t.Errorf("Map() modifies the original tree into %q, should be %q", s, want)
+	}
+	if got.siblings[0].parent != got {
+		t.Errorf("Map() returns a tree with invalid parents")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	root := transformTree()
+	want := root.String()
+
+	odd := func(nd *Node) bool { return nd.Data.(int)%2 == 1 }
+	notEven := func(nd *Node) bool { return nd.Data.(int) == 0 || odd(nd) }
+
+	tests := []struct {
+		keep func(*Node) bool
+		mode FilterMode
+		want string
+	}{
+		{notEven, DropSubtree, "0[1[3]]"},
+		{notEven, PromoteChildren, "0[1[3] 5]"},
+		{func(*Node) bool { return true }, DropSubtree, want},
+		{odd, PromoteChildren, "<nil>"},
+	}
+
+	for i, tst := range tests {
+		got := Filter(root, tst.keep, tst.mode)
+		s := "<nil>"
+		if got != nil {
+			s = got.String()
+		}
+		if s != tst.want {
+			t.Errorf("%d: Filter() returns %q, should be %q", i, s, tst.want)
+		}
+	}
+
+	// keep is called once for every node, so a stateful keep works
+	calls := 0
+	firstTwo := func(nd *Node) bool {
+		calls++
+		return calls <= 2
+	}
+	if got := Filter(root, firstTwo, DropSubtree); got == nil || got.String() != "0[1]" || calls != 5 {
+		t.Errorf("Filter() with a stateful keep returns %v after %d calls, should be 0[1] after 5", got, calls)
+	}
+
+	// the kept descendants of a dropped root are only returned by FilterForest
+	forest := FilterForest(root, odd, PromoteChildren)
+	if len(forest) != 2 || forest[0].String() != "1[3]" || forest[1].String() != "5" {
+		t.Errorf("FilterForest() returns %v, should be the trees 1[3] and 5", forest)
+	}
+	if forest := FilterForest(root, odd, DropSubtree); len(forest) != 0 {
+		t.Errorf("FilterForest() returns %d trees, should be 0", len(forest))
+	}
+
+	if s := root.String(); s != want {
+		t.Errorf("Filter() modifies the original tree into %q, should be %q", s, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	root := transformTree()
+
+	sum := func(nd *Node, childResults []interface{}) interface{} {
+		s := nd.Data.(int)
+		for _, r := range childResults {
+			s += r.(int)
+		}
+		return s
+	}
+	if got := Fold(root, sum); got != 21 {
+		t.Errorf("Fold(sum) returns %v, should be 21", got)
+	}
+
+	height := func(nd *Node, childResults []interface{}) interface{} {
+		h := 0
+		for _, r := range childResults {
+			if r.(int)+1 > h {
+				h = r.(int) + 1
+			}
+		}
+		return h
+	}
+	if got := Fold(root, height); got != root.Height() {
+		t.Errorf("Fold(height) returns %v, should be %d", got, root.Height())
+	}
+}
@@ -6,6 +6,9 @@ package otree
 // at the root of node. If sub is true it returns the size of the subtree for
 // which node is the root.
 func Breadth(node *Node, sub bool) int {
+	if r := selectRoot(node, sub); r.agg != nil {
+		return r.agg.leaves
+	}
 	breadth := 0
 
 	f := func(node *Node, data interface{}) {
@@ -44,6 +47,9 @@ func Height(node *Node, sub bool) int {
 // root of node. If sub is true it returns the size of the subtree starting at
 // node.
 func Size(node *Node, sub bool) int {
+	if r := selectRoot(node, sub); r.agg != nil {
+		return r.agg.size
+	}
 	n := 0
 
 	f := func(nd *Node, data interface{}) {
@@ -58,14 +64,78 @@ func Size(node *Node, sub bool) int {
 // tree starting at the root of node. If sub is true it returns the width of the
 // subtree starting at node.
 func Width(node *Node, level int, sub bool) int {
+	root := selectRoot(node, sub)
+	level -= root.Level()
 	width := 0
 
-	f := func(node *Node, data interface{}) {
-		if node.Level() == level {
+	root.walk(func(nd *Node, l int) bool {
+		if l == level {
 			width++
 		}
+		return true
+	})
+	return width
+}
+
+// TreeStats holds the statistics of a tree as returned by Stats.
+type TreeStats struct {
+	Size          int     // number of nodes
+	Breadth       int     // number of leaves
+	Internal      int     // number of internal nodes
+	Degree        int     // maximum degree of all nodes
+	Height        int     // longest downward path from the root to a leaf
+	Widths        []int   // number of nodes at each level, counted from the root
+	AvgBranching  float64 // average degree of the internal nodes
+	DeepestLeaves []*Node // leaves at the deepest level, in pre-order
+}
+
+// Stats returns the statistics of the tree starting at the root of node in a
+// single traversal. If sub is true it returns the statistics of the subtree
+// starting at node. In contrast to Width, the levels in the returned widths
+// are relative to the root of the (sub)tree.
+func Stats(node *Node, sub bool) TreeStats {
+	type item struct {
+		nd    *Node
+		level int
 	}
 
-	selectRoot(node, sub).Walk(f, nil)
-	return width
+	var st TreeStats
+	stack := []item{{selectRoot(node, sub), 0}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		st.Size++
+		if it.level == len(st.Widths) {
+			st.Widths = append(st.Widths, 0)
+		}
+		st.Widths[it.level]++
+
+		d := it.nd.Degree()
+		if d > st.Degree {
+			st.Degree = d
+		}
+		if d > 0 {
+			st.Internal++
+			sblngs := it.nd.Siblings()
+			for i := d - 1; i >= 0; i-- {
+				stack = append(stack, item{sblngs[i], it.level + 1})
+			}
+			continue
+		}
+
+		st.Breadth++
+		switch {
+		case it.level > st.Height:
+			st.Height = it.level
+			st.DeepestLeaves = []*Node{it.nd}
+		case it.level == st.Height:
+			st.DeepestLeaves = append(st.DeepestLeaves, it.nd)
+		}
+	}
+
+	if st.Internal > 0 {
+		st.AvgBranching = float64(st.Size-1) / float64(st.Internal)
+	}
+	return st
 }
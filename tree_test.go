@@ -93,3 +93,89 @@ func TestTreeHeight(t *testing.T) {
 //
 // 	fmt.Println(root.String()) // output: <root>[<10>[<20>,<21>],<11>,<12>,<13>]
 // }
+
+func TestStats(t *testing.T) {
+	root := New("root")
+	children := []*Node{New(10), New(11), New(12)}
+	grandChildren1 := []*Node{New(20), New(21), New(22), New(23)}
+	greatGrandChildren1 := []*Node{New(30), New(31)}
+
+	root.Link(0, children...)
+	children[1].Link(0, grandChildren1...)
+	grandChildren1[2].Link(0, greatGrandChildren1...)
+
+	st := Stats(grandChildren1[0], false)
+	if st.Size != Size(root, false) {
+		t.Errorf("Stats().Size is %d, should be %d", st.Size, Size(root, false))
+	}
+	if st.Breadth != Breadth(root, false) {
+		t.Errorf("Stats().Breadth is %d, should be %d", st.Breadth, Breadth(root, false))
+	}
+	if st.Degree != Degree(root, false) {
+		t.Errorf("Stats().Degree is %d, should be %d", st.Degree, Degree(root, false))
+	}
+	if st.Height != Height(root, false) {
+		t.Errorf("Stats().Height is %d, should be %d", st.Height, Height(root, false))
+	}
+	if st.Internal != 3 {
+		t.Errorf("Stats().Internal is %d, should be 3", st.Internal)
+	}
+	if st.AvgBranching != 3 {
+		t.Errorf("Stats().AvgBranching is %g, should be 3", st.AvgBranching)
+	}
+	if len(st.Widths) != 4 {
+		t.Errorf("len(Stats().Widths) is %d, should be 4", len(st.Widths))
+	} else {
+		for i, w := range st.Widths {
+			if want := Width(root, i, false); w != want {
+				t.Errorf("Stats().Widths[%d] is %d, should be %d", i, w, want)
+			}
+		}
+	}
+	if got := nodesString(st.DeepestLeaves); got != "[30@22 31@22]" {
+		t.Errorf("Stats().DeepestLeaves is %s, should be %s", got, "[30@22 31@22]")
+	}
+
+	st = Stats(children[1], true)
+	if st.Size != 7 || st.Height != 2 || len(st.Widths) != 3 || st.Widths[1] != 4 {
+		t.Errorf("Stats(sub) returns %+v", st)
+	}
+
+	st = Stats(New("leaf"), false)
+	if st.Size != 1 || st.Breadth != 1 || st.Internal != 0 || st.AvgBranching != 0 {
+		t.Errorf("Stats(leaf) returns %+v", st)
+	}
+}
+
+func TestDeepTreeMetrics(t *testing.T) {
+	const n = 1000000
+	root, last := deepChain(n)
+
+	if got := Size(root, false); got != n {
+		t.Errorf("Size() returns %d, should be %d", got, n)
+	}
+	if got := Breadth(last, false); got != 1 {
+		t.Errorf("Breadth() returns %d, should be 1", got)
+	}
+	if got := Degree(root, false); got != 1 {
+		t.Errorf("Degree() returns %d, should be 1", got)
+	}
+	if got := Height(last, false); got != n-1 {
+		t.Errorf("Height() returns %d, should be %d", got, n-1)
+	}
+	for _, level := range []int{0, n / 2, n - 1, n} {
+		want := 1
+		if level == n {
+			want = 0
+		}
+		if got := Width(root, level, false); got != want {
+			t.Errorf("Width(%d) returns %d, should be %d", level, got, want)
+		}
+	}
+	if got := Width(last, n-1, true); got != 1 {
+		t.Errorf("Width(%d, sub) returns %d, should be 1", n-1, got)
+	}
+	if st := Stats(root, false); st.Size != n || st.Height != n-1 || len(st.Widths) != n {
+		t.Errorf("Stats() returns size %d, height %d and %d widths", st.Size, st.Height, len(st.Widths))
+	}
+}
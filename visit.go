@@ -0,0 +1,56 @@
+package otree
+
+// Visitor is the interface implemented by types that visit the nodes of a
+// tree, see Visit. Enter is called when a node is reached, before its
+// siblings are visited. When it returns true for skipChildren, the siblings
+// of the node are not visited. Leave is called after the siblings are
+// visited, or skipped.
+type Visitor interface {
+	Enter(nd *Node) (skipChildren bool, err error)
+	Leave(nd *Node) error
+}
+
+// Visit visits root and all of its descendants in the order of Walk, calling
+// v.Enter before and v.Leave after visiting the siblings of a node. If one of
+// the calls returns an error Visit stops and returns it. Leave isn't called
+// for a node for which Enter returned an error. Visit doesn't use recursion,
+// so it can visit trees of any height.
+func Visit(root *Node, v Visitor) error {
+	type frame struct {
+		node     *Node
+		siblings []*Node
+		next     int
+	}
+	var stack []frame
+
+	nd := root
+	for {
+		if nd != nil {
+			skip, err := v.Enter(nd)
+			if err != nil {
+				return err
+			}
+			f := frame{node: nd}
+			if !skip {
+				f.siblings = nd.Siblings()
+			}
+			stack = append(stack, f)
+		}
+
+		l := len(stack)
+		if l == 0 {
+			return nil
+		}
+		top := &stack[l-1]
+		if top.next < len(top.siblings) {
+			nd = top.siblings[top.next]
+			top.next++
+			continue
+		}
+		if err := v.Leave(top.node); err != nil {
+			return err
+		}
+		stack = stack[:l-1]
+		nd = nil
+	}
+}
@@ -0,0 +1,96 @@
+package otree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tagVisitor writes nodes as open and close tags and numbers them as nested
+// sets.
+type tagVisitor struct {
+	sb       strings.Builder
+	skip     interface{}
+	fail     interface{}
+	n        int
+	lft, rgt map[interface{}]int
+}
+
+func (tv *tagVisitor) Enter(nd *Node) (bool, error) {
+	if nd.Data == tv.fail {
+		return false, errors.New("fail")
+	}
+	tv.n++
+	tv.lft[nd.Data] = tv.n
+	fmt.Fprintf(&tv.sb, "<%v>", nd.Data)
+	return nd.Data == tv.skip, nil
+}
+
+func (tv *tagVisitor) Leave(nd *Node) error {
+	tv.n++
+	tv.rgt[nd.Data] = tv.n
+	fmt.Fprintf(&tv.sb, "</%v>", nd.Data)
+	return nil
+}
+
+func TestVisit(t *testing.T) {
+	root := transformTree()
+
+	tests := []struct {
+		skip, fail interface{}
+		want       string
+		err        bool
+	}{
+		{nil, nil, "<0><1><3></3><4><6></6></4></1><2><5></5></2></0>", false},
+		{1, nil, "<0><1></1><2><5></5></2></0>", false},
+		{nil, 4, "<0><1><3></3>", true},
+	}
+	for i, tst := range tests {
+		tv := &tagVisitor{skip: tst.skip, fail: tst.fail,
+			lft: make(map[interface{}]int), rgt: make(map[interface{}]int)}
+		err := Visit(root, tv)
+		if (err != nil) != tst.err {
+			t.Errorf("%d: Visit() returns error %v", i, err)
+		}
+		if got := tv.sb.String(); got != tst.want {
+			t.Errorf("%d: Visit() results in %q, should be %q", i, got, tst.want)
+		}
+	}
+
+	tv := &tagVisitor{lft: make(map[interface{}]int), rgt: make(map[interface{}]int)}
+	Visit(root, tv)
+	if tv.lft[4] != 5 || tv.rgt[4] != 8 || tv.lft[0] != 1 || tv.rgt[0] != 14 {
+		t.Errorf("Visit() results in nested sets %v and %v", tv.lft, tv.rgt)
+	}
+}
+
+func TestVisitDeepTree(t *testing.T) {
+	const n = 1000000
+	root, _ := deepChain(n)
+
+	tv := &depthVisitor{}
+	if err := Visit(root, tv); err != nil {
+		t.Errorf("Visit() returns error %q, should be nil", err.Error())
+	}
+	if tv.max != n || tv.depth != 0 {
+		t.Errorf("Visit() reaches depth %d and ends at %d, should be %d and 0", tv.max, tv.depth, n)
+	}
+}
+
+// depthVisitor tracks the depth of the visited nodes.
+type depthVisitor struct {
+	depth, max int
+}
+
+func (dv *depthVisitor) Enter(nd *Node) (bool, error) {
+	if dv.depth++; dv.depth > dv.max {
+		dv.max = dv.depth
+	}
+	return false, nil
+}
+
+func (dv *depthVisitor) Leave(nd *Node) error {
+	dv.depth--
+	return nil
+}